@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/url"
 	"os"
+	"time"
+	_ "time/tzdata"
 
 	"chatapp/internal/domain/entity"
 	"chatapp/internal/infrastructure/database"
+	"chatapp/internal/infrastructure/mail"
+	"chatapp/internal/infrastructure/storage"
+	"chatapp/internal/interface/job"
 	"chatapp/internal/interface/router"
+	"chatapp/internal/usecase"
 
 	"github.com/labstack/echo/v4"
 )
@@ -33,12 +41,66 @@ func main() {
 	log.Println("Successfully connected to database:", db.Name())
 
 	// Migrate the database
-	database.Migrate(db, &entity.User{})
+	if err := database.Migrate(db, &entity.User{}, &entity.EmailChange{}, &entity.DataExport{}, &entity.UserRelation{}); err != nil {
+		log.Fatal(err)
+	}
 	log.Println("Successfully migrated database")
 
+	// Links in emails point to the client, they must be absolute to work from a mailbox
+	appURL := os.Getenv("APP_URL")
+	if u, err := url.Parse(appURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Fatalf("APP_URL must be an absolute http or https URL, got %q", appURL)
+	}
+
+	// Set up mailer, emails are only logged when MAIL_DRIVER=log is set explicitly
+	var mailer usecase.Mailer
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "", "smtp":
+		smtpConfig, err := mail.NewSMTPConfig(
+			os.Getenv("SMTP_HOST"),
+			os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			os.Getenv("SMTP_FROM"),
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mailer = mail.NewSMTPMailer(smtpConfig)
+	case "log":
+		log.Println("MAIL_DRIVER is log, emails and the tokens in them are written to the log")
+		mailer = mail.NewLogMailer()
+	default:
+		log.Fatalf("unknown MAIL_DRIVER %q, use smtp or log", driver)
+	}
+
+	// Purge deleted users past the retention period in the background
+	userPurgeUseCase := usecase.NewUserUseCase(database.NewUserRepository(db), database.NewEmailChangeRepository(db))
+	go job.NewUserPurgeJob(userPurgeUseCase, time.Hour).Run(context.Background())
+
+	// Set up storage for data export archives, every instance must mount the same directory
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		log.Fatal("EXPORT_DIR must be set to a directory shared by every instance")
+	}
+	exportStore, err := storage.NewLocalStore(exportDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Build requested data exports and delete the expired ones in the background
+	dataExportUseCase := usecase.NewDataExportUseCase(
+		database.NewUserRepository(db),
+		database.NewEmailChangeRepository(db),
+		database.NewUserRelationRepository(db),
+		database.NewDataExportRepository(db),
+		exportStore,
+	)
+	go job.NewDataExportJob(dataExportUseCase, 10*time.Second).Run(context.Background())
+
 	// Set up router
 	e := echo.New()
-	handlers := router.InitRouter(db)
+	handlers := router.InitRouter(db, mailer, exportStore, appURL)
 	handlers.SetUpRouter(e)
 
 	e.Logger.Fatal(e.Start(":" + os.Getenv("APP_PORT")))
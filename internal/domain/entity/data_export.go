@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DataExportTTL is how long a finished export can be downloaded
+const DataExportTTL = 24 * time.Hour
+
+// maxExportErrorLength matches the size of DataExport.Error
+const maxExportErrorLength = 255
+
+type DataExportStatus string
+
+const (
+	DataExportPending    DataExportStatus = "pending"
+	DataExportProcessing DataExportStatus = "processing"
+	DataExportReady      DataExportStatus = "ready"
+	DataExportFailed     DataExportStatus = "failed"
+	DataExportExpired    DataExportStatus = "expired"
+)
+
+// DataExport is a request from a user for an archive of their personal data.
+// It is built in the background, then downloadable with its token until it expires.
+type DataExport struct {
+	gorm.Model
+	UserID            uint             `gorm:"not null; index"`
+	Status            DataExportStatus `gorm:"not null; size:16; index; default:'pending'"`
+	DownloadTokenHash string           `gorm:"not null; size:64; uniqueIndex"`
+	FileKey           string           `gorm:"not null; size:255; default:''"`
+	Error             string           `gorm:"not null; size:255; default:''"`
+	ExpiresAt         *time.Time
+}
+
+// NewDataExport creates a pending export for the user and returns it with its download token
+func NewDataExport(userID uint) (*DataExport, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	export := &DataExport{
+		UserID:            userID,
+		Status:            DataExportPending,
+		DownloadTokenHash: HashToken(token),
+	}
+
+	return export, token, nil
+}
+
+// Complete marks the export as ready to download from the stored file
+func (e *DataExport) Complete(fileKey string, now time.Time) error {
+	if e.Status != DataExportProcessing {
+		return fmt.Errorf("cannot complete a %s export", e.Status)
+	}
+
+	expiresAt := now.Add(DataExportTTL)
+	e.Status = DataExportReady
+	e.FileKey = fileKey
+	e.ExpiresAt = &expiresAt
+
+	return nil
+}
+
+// Fail marks the export as failed with the reason
+func (e *DataExport) Fail(reason error) {
+	message := reason.Error()
+	if len(message) > maxExportErrorLength {
+		message = message[:maxExportErrorLength]
+	}
+
+	e.Status = DataExportFailed
+	e.Error = message
+}
+
+// Expire marks the export as expired, its file is expected to be deleted
+func (e *DataExport) Expire() {
+	e.Status = DataExportExpired
+	e.FileKey = ""
+}
+
+// IsExpired reports whether a ready export is past its download period
+func (e *DataExport) IsExpired(now time.Time) bool {
+	return e.Status == DataExportReady && e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// CanDownload reports whether the export is ready, unexpired and the token is its download token
+func (e *DataExport) CanDownload(token string, now time.Time) bool {
+	return e.Status == DataExportReady && !e.IsExpired(now) && tokenMatches(token, e.DownloadTokenHash)
+}
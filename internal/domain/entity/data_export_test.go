@@ -0,0 +1,105 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDataExport(t *testing.T) {
+	export, token, err := NewDataExport(1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), export.UserID)
+	assert.Equal(t, DataExportPending, export.Status)
+	assert.Equal(t, HashToken(token), export.DownloadTokenHash)
+}
+
+func TestDataExportComplete(t *testing.T) {
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		status  DataExportStatus
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			status:  DataExportProcessing,
+			wantErr: false,
+		},
+		{
+			name:    "fail because not processing",
+			status:  DataExportPending,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			export := &DataExport{Status: test.status}
+			err := export.Complete("1.zip", now)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.status, export.Status)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, DataExportReady, export.Status)
+				assert.Equal(t, "1.zip", export.FileKey)
+				assert.Equal(t, now.Add(DataExportTTL), *export.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestDataExportFail(t *testing.T) {
+	export := &DataExport{Status: DataExportProcessing}
+	export.Fail(errors.New(strings.Repeat("a", 300)))
+	assert.Equal(t, DataExportFailed, export.Status)
+	assert.Equal(t, maxExportErrorLength, len(export.Error))
+}
+
+func TestDataExportCanDownload(t *testing.T) {
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		export DataExport
+		token  string
+		want   bool
+	}{
+		{
+			name:   "ready with the right token",
+			export: DataExport{Status: DataExportReady, DownloadTokenHash: HashToken("token"), ExpiresAt: &later},
+			token:  "token",
+			want:   true,
+		},
+		{
+			name:   "wrong token",
+			export: DataExport{Status: DataExportReady, DownloadTokenHash: HashToken("token"), ExpiresAt: &later},
+			token:  "other",
+			want:   false,
+		},
+		{
+			name:   "not ready",
+			export: DataExport{Status: DataExportProcessing, DownloadTokenHash: HashToken("token")},
+			token:  "token",
+			want:   false,
+		},
+		{
+			name:   "expired",
+			export: DataExport{Status: DataExportReady, DownloadTokenHash: HashToken("token"), ExpiresAt: &now},
+			token:  "token",
+			want:   false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.export.CanDownload(test.token, now))
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// EmailChangeConfirmTTL is how long the link sent to the new address can be used
+	EmailChangeConfirmTTL = 24 * time.Hour
+	// EmailChangeRevertTTL is how long the link sent to the old address can be used
+	EmailChangeRevertTTL = 7 * 24 * time.Hour
+)
+
+// EmailChange is a request to change the email of a user.
+// It is pending until confirmed from the new address, and once confirmed
+// it can be reverted from the old address for a while.
+type EmailChange struct {
+	gorm.Model
+	UserID           uint      `gorm:"not null; index"`
+	OldEmail         string    `gorm:"not null; size:255; check:old_email <> ''"`
+	NewEmail         string    `gorm:"not null; size:255; check:new_email <> ''"`
+	ConfirmTokenHash string    `gorm:"not null; size:64; uniqueIndex"`
+	ConfirmExpiresAt time.Time `gorm:"not null"`
+	ConfirmedAt      *time.Time
+	RevertTokenHash  *string `gorm:"size:64; uniqueIndex"`
+	RevertExpiresAt  *time.Time
+	RevertedAt       *time.Time
+}
+
+// NewEmailChange creates a pending email change for the user and returns it with its confirmation token
+func NewEmailChange(user *User, newEmail string, now time.Time) (*EmailChange, string, error) {
+	if err := ValidateEmail(newEmail); err != nil {
+		return nil, "", err
+	}
+	if newEmail == user.Email {
+		return nil, "", fmt.Errorf("new email must differ from the current email")
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	change := &EmailChange{
+		UserID:           user.ID,
+		OldEmail:         user.Email,
+		NewEmail:         newEmail,
+		ConfirmTokenHash: HashToken(token),
+		ConfirmExpiresAt: now.Add(EmailChangeConfirmTTL),
+	}
+
+	return change, token, nil
+}
+
+// Confirm switches the user to the new email and returns a token to revert the change
+func (c *EmailChange) Confirm(user *User, now time.Time) (string, error) {
+	if c.ConfirmedAt != nil {
+		return "", fmt.Errorf("email change already confirmed")
+	}
+	if !now.Before(c.ConfirmExpiresAt) {
+		return "", fmt.Errorf("email change confirmation expired")
+	}
+	if user.ID != c.UserID || user.Email != c.OldEmail {
+		return "", fmt.Errorf("email changed since the request was made")
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	tokenHash := HashToken(token)
+	revertExpiresAt := now.Add(EmailChangeRevertTTL)
+	c.ConfirmedAt = &now
+	c.RevertTokenHash = &tokenHash
+	c.RevertExpiresAt = &revertExpiresAt
+	user.Email = c.NewEmail
+
+	return token, nil
+}
+
+// Revert switches the user back to the old email
+func (c *EmailChange) Revert(user *User, now time.Time) error {
+	if c.ConfirmedAt == nil || c.RevertExpiresAt == nil {
+		return fmt.Errorf("email change not confirmed")
+	}
+	if c.RevertedAt != nil {
+		return fmt.Errorf("email change already reverted")
+	}
+	if !now.Before(*c.RevertExpiresAt) {
+		return fmt.Errorf("email change revert expired")
+	}
+	if user.ID != c.UserID {
+		return fmt.Errorf("email change belongs to another user")
+	}
+
+	c.RevertedAt = &now
+	user.Email = c.OldEmail
+
+	return nil
+}
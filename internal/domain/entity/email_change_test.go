@@ -0,0 +1,159 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestNewEmailChange(t *testing.T) {
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	user := &User{Model: gorm.Model{ID: 1}, Email: "old@test.com"}
+
+	tests := []struct {
+		name     string
+		newEmail string
+		wantErr  bool
+	}{
+		{
+			name:     "success",
+			newEmail: "new@test.com",
+			wantErr:  false,
+		},
+		{
+			name:     "fail because email is invalid",
+			newEmail: "invalid",
+			wantErr:  true,
+		},
+		{
+			name:     "fail because email is unchanged",
+			newEmail: "old@test.com",
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			change, token, err := NewEmailChange(user, test.newEmail, now)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, change)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, user.ID, change.UserID)
+				assert.Equal(t, "old@test.com", change.OldEmail)
+				assert.Equal(t, test.newEmail, change.NewEmail)
+				assert.Equal(t, HashToken(token), change.ConfirmTokenHash)
+				assert.NotEqual(t, token, change.ConfirmTokenHash)
+				assert.Equal(t, now.Add(EmailChangeConfirmTTL), change.ConfirmExpiresAt)
+			}
+		})
+	}
+}
+
+func TestEmailChangeConfirm(t *testing.T) {
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	confirmedAt := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		change  EmailChange
+		user    User
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			change:  EmailChange{UserID: 1, OldEmail: "old@test.com", NewEmail: "new@test.com", ConfirmExpiresAt: now.Add(time.Hour)},
+			user:    User{Model: gorm.Model{ID: 1}, Email: "old@test.com"},
+			wantErr: false,
+		},
+		{
+			name:    "fail because already confirmed",
+			change:  EmailChange{UserID: 1, OldEmail: "old@test.com", NewEmail: "new@test.com", ConfirmExpiresAt: now.Add(time.Hour), ConfirmedAt: &confirmedAt},
+			user:    User{Model: gorm.Model{ID: 1}, Email: "old@test.com"},
+			wantErr: true,
+		},
+		{
+			name:    "fail because expired",
+			change:  EmailChange{UserID: 1, OldEmail: "old@test.com", NewEmail: "new@test.com", ConfirmExpiresAt: now},
+			user:    User{Model: gorm.Model{ID: 1}, Email: "old@test.com"},
+			wantErr: true,
+		},
+		{
+			name:    "fail because email changed in the meantime",
+			change:  EmailChange{UserID: 1, OldEmail: "old@test.com", NewEmail: "new@test.com", ConfirmExpiresAt: now.Add(time.Hour)},
+			user:    User{Model: gorm.Model{ID: 1}, Email: "other@test.com"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			change, user := test.change, test.user
+			token, err := change.Confirm(&user, now)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.user.Email, user.Email)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "new@test.com", user.Email)
+				assert.Equal(t, now, *change.ConfirmedAt)
+				assert.Equal(t, HashToken(token), *change.RevertTokenHash)
+				assert.Equal(t, now.Add(EmailChangeRevertTTL), *change.RevertExpiresAt)
+			}
+		})
+	}
+}
+
+func TestEmailChangeRevert(t *testing.T) {
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	confirmedAt := now.Add(-time.Hour)
+	revertExpiresAt := now.Add(time.Hour)
+	expiredAt := now
+	revertedAt := now.Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		change  EmailChange
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			change:  EmailChange{UserID: 1, OldEmail: "old@test.com", NewEmail: "new@test.com", ConfirmedAt: &confirmedAt, RevertExpiresAt: &revertExpiresAt},
+			wantErr: false,
+		},
+		{
+			name:    "fail because not confirmed",
+			change:  EmailChange{UserID: 1, OldEmail: "old@test.com", NewEmail: "new@test.com"},
+			wantErr: true,
+		},
+		{
+			name:    "fail because already reverted",
+			change:  EmailChange{UserID: 1, OldEmail: "old@test.com", NewEmail: "new@test.com", ConfirmedAt: &confirmedAt, RevertExpiresAt: &revertExpiresAt, RevertedAt: &revertedAt},
+			wantErr: true,
+		},
+		{
+			name:    "fail because expired",
+			change:  EmailChange{UserID: 1, OldEmail: "old@test.com", NewEmail: "new@test.com", ConfirmedAt: &confirmedAt, RevertExpiresAt: &expiredAt},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			change := test.change
+			user := User{Model: gorm.Model{ID: 1}, Email: "new@test.com"}
+			err := change.Revert(&user, now)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, "new@test.com", user.Email)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "old@test.com", user.Email)
+				assert.Equal(t, now, *change.RevertedAt)
+			}
+		})
+	}
+}
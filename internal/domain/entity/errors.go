@@ -0,0 +1,9 @@
+package entity
+
+import "errors"
+
+// ErrAlreadyExists is returned by repositories when a unique field is already taken
+var ErrAlreadyExists = errors.New("already exists")
+
+// ErrStaleUpdate is returned by repositories when a concurrent request already changed the record
+var ErrStaleUpdate = errors.New("updated concurrently")
@@ -2,21 +2,75 @@ package entity
 
 import (
 	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"chatapp/pkg/mention"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// DeletedAccountRetention is how long a deleted account can be restored before it is purged
+const DeletedAccountRetention = 30 * 24 * time.Hour
+
+const (
+	maxNameLength        = 255
+	maxEmailLength       = 255
+	maxDisplayNameLength = 64
+	maxBioLength         = 500
+	maxLocaleLength      = 35
+	defaultTimezone      = "UTC"
+	defaultLocale        = "en"
+)
+
+var (
+	// handlePattern is the format of a user handle, the name used in @mentions
+	handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+	// localePattern is a BCP 47 language tag such as "en" or "pt-BR"
+	localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+)
+
+// User is an account. Deleted users keep their handle and email until purged, so that they can be restored.
 type User struct {
 	gorm.Model
-	Name     string `gorm:"not null; size:255; check:name <> ''"`
-	Email    string `gorm:"not null; size:255; unique; check:email <> ''"`
-	Password string `gorm:"not null; size:255; check:password <> ''"`
+	Name        string `gorm:"not null; size:255; check:name <> ''"`
+	Handle      string `gorm:"not null; size:30; uniqueIndex:idx_users_unique_handle; check:handle <> ''"`
+	Email       string `gorm:"not null; size:255; uniqueIndex:idx_users_unique_email; check:email <> ''"`
+	Password    string `gorm:"not null; size:255; check:password <> ''"`
+	DisplayName string `gorm:"not null; size:64; default:''"`
+	Bio         string `gorm:"not null; size:500; default:''"`
+	Timezone    string `gorm:"not null; size:64; default:'UTC'"`
+	Locale      string `gorm:"not null; size:35; default:'en'"`
+}
+
+// ProfileUpdate holds the profile fields to change, nil fields are left as they are
+type ProfileUpdate struct {
+	Handle      *string
+	DisplayName *string
+	Bio         *string
+	Timezone    *string
+	Locale      *string
 }
 
-func NewUser(name, email, password string) (*User, error) {
-	if name == "" || email == "" || password == "" {
-		return nil, fmt.Errorf("name, email, and password must not be empty")
+func NewUser(name, handle, email, password string) (*User, error) {
+	if name == "" || handle == "" || email == "" || password == "" {
+		return nil, fmt.Errorf("name, handle, email, and password must not be empty")
+	}
+
+	// Sign-up applies the same rules as later updates, or the user could not save their account again
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	if err := ValidateHandle(handle); err != nil {
+		return nil, err
+	}
+	if err := ValidateEmail(email); err != nil {
+		return nil, err
 	}
 
 	hashedPassword, err := hashPassword(password)
@@ -26,13 +80,156 @@ func NewUser(name, email, password string) (*User, error) {
 
 	user := &User{
 		Name:     name,
+		Handle:   handle,
 		Email:    email,
 		Password: hashedPassword,
+		Timezone: defaultTimezone,
+		Locale:   defaultLocale,
 	}
 
 	return user, nil
 }
 
+// ValidateName checks that a name is not blank and at most 255 characters
+func ValidateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxNameLength)
+	}
+	return nil
+}
+
+// ValidateEmail checks that an email is a bare address such as "user@example.com"
+func ValidateEmail(email string) error {
+	if email == "" {
+		return fmt.Errorf("email must not be empty")
+	}
+	if len(email) > maxEmailLength {
+		return fmt.Errorf("email must be at most %d characters", maxEmailLength)
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return fmt.Errorf("invalid email: %q", email)
+	}
+	return nil
+}
+
+// ValidateHandle checks that a handle is 3 to 30 lowercase letters, digits or underscores
+// and is not one of the group mentions such as @here
+func ValidateHandle(handle string) error {
+	if !handlePattern.MatchString(handle) {
+		return fmt.Errorf("handle must be 3 to 30 lowercase letters, digits, or underscores")
+	}
+	if mention.IsSpecial(handle) {
+		return fmt.Errorf("handle %q is reserved", handle)
+	}
+	return nil
+}
+
+// ValidateDisplayName checks that a display name is at most 64 characters
+func ValidateDisplayName(displayName string) error {
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return fmt.Errorf("display name must be at most %d characters", maxDisplayNameLength)
+	}
+	return nil
+}
+
+// ValidateBio checks that a bio is at most 500 characters
+func ValidateBio(bio string) error {
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return fmt.Errorf("bio must be at most %d characters", maxBioLength)
+	}
+	return nil
+}
+
+// ValidateTimezone checks that a timezone is an IANA time zone name such as "Asia/Tokyo"
+func ValidateTimezone(timezone string) error {
+	if timezone == "" || timezone == "Local" {
+		return fmt.Errorf("invalid timezone: %q", timezone)
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %q", timezone)
+	}
+	return nil
+}
+
+// ValidateLocale checks that a locale is a language tag such as "en" or "ja-JP" of at most 35 characters
+func ValidateLocale(locale string) error {
+	if len(locale) > maxLocaleLength {
+		return fmt.Errorf("locale must be at most %d characters", maxLocaleLength)
+	}
+	if !localePattern.MatchString(locale) {
+		return fmt.Errorf("invalid locale: %q", locale)
+	}
+	return nil
+}
+
+// UpdateProfile validates every given field and applies them only if all are valid
+func (u *User) UpdateProfile(update ProfileUpdate) error {
+	validations := []struct {
+		value    *string
+		validate func(string) error
+	}{
+		{update.Handle, ValidateHandle},
+		{update.DisplayName, ValidateDisplayName},
+		{update.Bio, ValidateBio},
+		{update.Timezone, ValidateTimezone},
+		{update.Locale, ValidateLocale},
+	}
+	for _, v := range validations {
+		if v.value == nil {
+			continue
+		}
+		if err := v.validate(*v.value); err != nil {
+			return err
+		}
+	}
+
+	if update.Handle != nil {
+		u.Handle = *update.Handle
+	}
+	if update.DisplayName != nil {
+		u.DisplayName = strings.TrimSpace(*update.DisplayName)
+	}
+	if update.Bio != nil {
+		u.Bio = strings.TrimSpace(*update.Bio)
+	}
+	if update.Timezone != nil {
+		u.Timezone = *update.Timezone
+	}
+	if update.Locale != nil {
+		u.Locale = *update.Locale
+	}
+
+	return nil
+}
+
+// Initials returns up to two initials from the display name, or the name if none is set,
+// used as the avatar when the user has no picture
+func (u *User) Initials() string {
+	name := u.DisplayName
+	if name == "" {
+		name = u.Name
+	}
+
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+	if len(words) > 2 {
+		words = []string{words[0], words[len(words)-1]}
+	}
+
+	var initials []rune
+	for _, word := range words {
+		r, _ := utf8.DecodeRuneInString(word)
+		initials = append(initials, unicode.ToUpper(r))
+	}
+	return string(initials)
+}
+
 func hashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -41,6 +238,11 @@ func hashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
+// IsRestorable reports whether the user is deleted and still within the retention period
+func (u *User) IsRestorable(now time.Time) bool {
+	return u.DeletedAt.Valid && now.Before(u.DeletedAt.Time.Add(DeletedAccountRetention))
+}
+
 func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
@@ -0,0 +1,52 @@
+package entity
+
+import "time"
+
+// UserSortField is a column users can be listed by
+type UserSortField string
+
+const (
+	UserSortByID        UserSortField = "id"
+	UserSortByName      UserSortField = "name"
+	UserSortByHandle    UserSortField = "handle"
+	UserSortByCreatedAt UserSortField = "created_at"
+)
+
+// UserCursor points at the last user of a page, by its sort value and ID
+type UserCursor struct {
+	Value string
+	ID    uint
+}
+
+// UserListQuery describes one page of a user listing
+type UserListQuery struct {
+	Prefix string
+	SortBy UserSortField
+	Desc   bool
+	After  *UserCursor
+	Limit  int
+}
+
+// CursorValue returns the value of the sort field for the user, as stored in a cursor
+func (f UserSortField) CursorValue(user *User) string {
+	switch f {
+	case UserSortByName:
+		return user.Name
+	case UserSortByHandle:
+		return user.Handle
+	case UserSortByCreatedAt:
+		return user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return ""
+	}
+}
+
+// IsValid reports whether users can be sorted by the field
+func (f UserSortField) IsValid() bool {
+	switch f {
+	case UserSortByID, UserSortByName, UserSortByHandle, UserSortByCreatedAt:
+		return true
+	default:
+		return false
+	}
+}
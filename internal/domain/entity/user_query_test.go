@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestUserSortFieldCursorValue(t *testing.T) {
+	createdAt := time.Date(2023, 10, 1, 12, 30, 0, 500, time.UTC)
+	user := &User{
+		Model:  gorm.Model{ID: 1, CreatedAt: createdAt},
+		Name:   "test",
+		Handle: "test_handle",
+	}
+
+	tests := []struct {
+		name  string
+		field UserSortField
+		want  string
+	}{
+		{
+			name:  "id",
+			field: UserSortByID,
+			want:  "",
+		},
+		{
+			name:  "name",
+			field: UserSortByName,
+			want:  "test",
+		},
+		{
+			name:  "handle",
+			field: UserSortByHandle,
+			want:  "test_handle",
+		},
+		{
+			name:  "created_at",
+			field: UserSortByCreatedAt,
+			want:  "2023-10-01T12:30:00.0000005Z",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.field.CursorValue(user))
+		})
+	}
+}
+
+func TestUserSortFieldIsValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		field UserSortField
+		want  bool
+	}{
+		{
+			name:  "valid field",
+			field: UserSortByName,
+			want:  true,
+		},
+		{
+			name:  "unknown field",
+			field: UserSortField("password"),
+			want:  false,
+		},
+		{
+			name:  "empty field",
+			field: UserSortField(""),
+			want:  false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.field.IsValid())
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type UserRelationKind string
+
+const (
+	// UserRelationBlock hides the user from the target: no DMs, mention notifications or presence
+	UserRelationBlock UserRelationKind = "block"
+	// UserRelationMute collapses the messages of the target and silences their notifications
+	UserRelationMute UserRelationKind = "mute"
+)
+
+// IsValid reports whether the kind is a known relation kind
+func (k UserRelationKind) IsValid() bool {
+	switch k {
+	case UserRelationBlock, UserRelationMute:
+		return true
+	}
+	return false
+}
+
+// UserRelation is a block or a mute set by a user on another user
+type UserRelation struct {
+	gorm.Model
+	UserID   uint             `gorm:"not null; uniqueIndex:idx_user_relations_pair"`
+	TargetID uint             `gorm:"not null; uniqueIndex:idx_user_relations_pair; index"`
+	Kind     UserRelationKind `gorm:"not null; size:16; uniqueIndex:idx_user_relations_pair"`
+}
+
+// NewUserRelation creates a relation of the kind from the user to the target
+func NewUserRelation(userID, targetID uint, kind UserRelationKind) (*UserRelation, error) {
+	if !kind.IsValid() {
+		return nil, fmt.Errorf("invalid relation kind: %s", kind)
+	}
+	if userID == targetID {
+		return nil, fmt.Errorf("cannot %s yourself", kind)
+	}
+
+	return &UserRelation{
+		UserID:   userID,
+		TargetID: targetID,
+		Kind:     kind,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUserRelation(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   uint
+		targetID uint
+		kind     UserRelationKind
+		wantErr  bool
+	}{
+		{
+			name:     "success block",
+			userID:   1,
+			targetID: 2,
+			kind:     UserRelationBlock,
+			wantErr:  false,
+		},
+		{
+			name:     "success mute",
+			userID:   1,
+			targetID: 2,
+			kind:     UserRelationMute,
+			wantErr:  false,
+		},
+		{
+			name:     "error yourself",
+			userID:   1,
+			targetID: 1,
+			kind:     UserRelationBlock,
+			wantErr:  true,
+		},
+		{
+			name:     "error invalid kind",
+			userID:   1,
+			targetID: 2,
+			kind:     "follow",
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			relation, err := NewUserRelation(test.userID, test.targetID, test.kind)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, relation)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.userID, relation.UserID)
+				assert.Equal(t, test.targetID, relation.TargetID)
+				assert.Equal(t, test.kind, relation.Kind)
+			}
+		})
+	}
+}
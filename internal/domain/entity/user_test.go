@@ -1,9 +1,12 @@
 package entity
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 func TestNewUser(t *testing.T) {
@@ -16,6 +19,7 @@ func TestNewUser(t *testing.T) {
 			name: "success to create a new user",
 			input: map[string]string{
 				"name":     "test",
+				"handle":   "test",
 				"email":    "test@test.com",
 				"password": "password",
 			},
@@ -25,6 +29,47 @@ func TestNewUser(t *testing.T) {
 			name: "fail to create a new user because name is empty",
 			input: map[string]string{
 				"name":     "",
+				"handle":   "test",
+				"email":    "test@test.com",
+				"password": "password",
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail to create a new user because handle is empty",
+			input: map[string]string{
+				"name":     "test",
+				"handle":   "",
+				"email":    "test@test.com",
+				"password": "password",
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail to create a new user because handle has invalid characters",
+			input: map[string]string{
+				"name":     "test",
+				"handle":   "Test-User",
+				"email":    "test@test.com",
+				"password": "password",
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail to create a new user because handle is too short",
+			input: map[string]string{
+				"name":     "test",
+				"handle":   "ab",
+				"email":    "test@test.com",
+				"password": "password",
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail to create a new user because handle is reserved for a group mention",
+			input: map[string]string{
+				"name":     "test",
+				"handle":   "here",
 				"email":    "test@test.com",
 				"password": "password",
 			},
@@ -34,15 +79,47 @@ func TestNewUser(t *testing.T) {
 			name: "fail to create a new user because email is empty",
 			input: map[string]string{
 				"name":     "test",
+				"handle":   "test",
 				"email":    "",
 				"password": "password",
 			},
 			wantErr: true,
 		},
+		{
+			name: "fail to create a new user because email is invalid",
+			input: map[string]string{
+				"name":     "test",
+				"handle":   "test",
+				"email":    "foo",
+				"password": "password",
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail to create a new user because name is too long",
+			input: map[string]string{
+				"name":     strings.Repeat("a", 256),
+				"handle":   "test",
+				"email":    "test@test.com",
+				"password": "password",
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail to create a new user because name is blank",
+			input: map[string]string{
+				"name":     "   ",
+				"handle":   "test",
+				"email":    "test@test.com",
+				"password": "password",
+			},
+			wantErr: true,
+		},
 		{
 			name: "fail to create a new user because password is empty",
 			input: map[string]string{
 				"name":     "test",
+				"handle":   "test",
 				"email":    "test@test.com",
 				"password": "",
 			},
@@ -52,7 +129,7 @@ func TestNewUser(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			user, err := NewUser(test.input["name"], test.input["email"], test.input["password"])
+			user, err := NewUser(test.input["name"], test.input["handle"], test.input["email"], test.input["password"])
 			if test.wantErr {
 				assert.Error(t, err)
 				assert.Nil(t, user)
@@ -60,6 +137,7 @@ func TestNewUser(t *testing.T) {
 				assert.NoError(t, err)
 				assert.NotNil(t, user)
 				assert.Equal(t, test.input["name"], user.Name)
+				assert.Equal(t, test.input["handle"], user.Handle)
 				assert.Equal(t, test.input["email"], user.Email)
 				// Password must not equal because the password is hashed
 				assert.NotEqual(t, test.input["password"], user.Password)
@@ -67,3 +145,267 @@ func TestNewUser(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateProfile(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		input   ProfileUpdate
+		want    User
+		wantErr bool
+	}{
+		{
+			name: "success to update every field",
+			input: ProfileUpdate{
+				Handle:      str("new_handle"),
+				DisplayName: str("  Test User  "),
+				Bio:         str("hello"),
+				Timezone:    str("Asia/Tokyo"),
+				Locale:      str("ja-JP"),
+			},
+			want: User{
+				Name:        "test",
+				Handle:      "new_handle",
+				DisplayName: "Test User",
+				Bio:         "hello",
+				Timezone:    "Asia/Tokyo",
+				Locale:      "ja-JP",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "success to leave omitted fields as they are",
+			input: ProfileUpdate{Bio: str("")},
+			want: User{
+				Name:     "test",
+				Handle:   "test",
+				Timezone: "UTC",
+				Locale:   "en",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "fail because handle is invalid",
+			input:   ProfileUpdate{Handle: str("Invalid Handle")},
+			wantErr: true,
+		},
+		{
+			name:    "fail because display name is too long",
+			input:   ProfileUpdate{DisplayName: str(strings.Repeat("あ", 65))},
+			wantErr: true,
+		},
+		{
+			name:    "fail because bio is too long",
+			input:   ProfileUpdate{Bio: str(strings.Repeat("a", 501))},
+			wantErr: true,
+		},
+		{
+			name:    "fail because timezone is unknown",
+			input:   ProfileUpdate{Timezone: str("Mars/Olympus")},
+			wantErr: true,
+		},
+		{
+			name:    "fail because timezone is local",
+			input:   ProfileUpdate{Timezone: str("Local")},
+			wantErr: true,
+		},
+		{
+			name:    "fail because locale is invalid",
+			input:   ProfileUpdate{Locale: str("english!")},
+			wantErr: true,
+		},
+		{
+			name:    "fail because locale is too long",
+			input:   ProfileUpdate{Locale: str("en-abcdefgh-abcdefgh-abcdefgh-abcdefgh")},
+			wantErr: true,
+		},
+		{
+			name: "fail without applying valid fields when one is invalid",
+			input: ProfileUpdate{
+				DisplayName: str("Test User"),
+				Locale:      str("-"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user, _ := NewUser("test", "test", "test@test.com", "password")
+			before := *user
+
+			err := user.UpdateProfile(test.input)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, before, *user)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.want.Name, user.Name)
+				assert.Equal(t, test.want.Handle, user.Handle)
+				assert.Equal(t, test.want.DisplayName, user.DisplayName)
+				assert.Equal(t, test.want.Bio, user.Bio)
+				assert.Equal(t, test.want.Timezone, user.Timezone)
+				assert.Equal(t, test.want.Locale, user.Locale)
+			}
+		})
+	}
+}
+
+func TestInitials(t *testing.T) {
+	tests := []struct {
+		name  string
+		input User
+		want  string
+	}{
+		{
+			name:  "from name",
+			input: User{Name: "test"},
+			want:  "T",
+		},
+		{
+			name:  "from display name",
+			input: User{Name: "test", DisplayName: "ada lovelace"},
+			want:  "AL",
+		},
+		{
+			name:  "from first and last word",
+			input: User{Name: "John Ronald Reuel Tolkien"},
+			want:  "JT",
+		},
+		{
+			name:  "from non ascii name",
+			input: User{Name: "山田 太郎"},
+			want:  "山太",
+		},
+		{
+			name:  "empty",
+			input: User{},
+			want:  "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.input.Initials())
+		})
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			input:   "test",
+			wantErr: false,
+		},
+		{
+			name:    "empty",
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "blank",
+			input:   "   ",
+			wantErr: true,
+		},
+		{
+			name:    "too long",
+			input:   strings.Repeat("a", 256),
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateName(test.input)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			input:   "test@test.com",
+			wantErr: false,
+		},
+		{
+			name:    "empty",
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "missing domain",
+			input:   "test",
+			wantErr: true,
+		},
+		{
+			name:    "with display name",
+			input:   "Test <test@test.com>",
+			wantErr: true,
+		},
+		{
+			name:    "too long",
+			input:   strings.Repeat("a", 250) + "@test.com",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateEmail(test.input)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestIsRestorable(t *testing.T) {
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		deletedAt gorm.DeletedAt
+		want      bool
+	}{
+		{
+			name:      "not deleted",
+			deletedAt: gorm.DeletedAt{},
+			want:      false,
+		},
+		{
+			name:      "deleted within retention",
+			deletedAt: gorm.DeletedAt{Time: now.Add(-DeletedAccountRetention + time.Hour), Valid: true},
+			want:      true,
+		},
+		{
+			name:      "deleted past retention",
+			deletedAt: gorm.DeletedAt{Time: now.Add(-DeletedAccountRetention), Valid: true},
+			want:      false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user := &User{Model: gorm.Model{DeletedAt: test.deletedAt}}
+			assert.Equal(t, test.want, user.IsRestorable(now))
+		})
+	}
+}
@@ -32,11 +32,11 @@ func TestMain(m *testing.M) {
 	}
 
 	// Migrate test database
-	testDB.AutoMigrate(&entity.User{})
+	testDB.AutoMigrate(&entity.User{}, &entity.EmailChange{}, &entity.DataExport{}, &entity.UserRelation{})
 
 	// Tear down test database
 	defer func() {
-		if err := testDB.Migrator().DropTable(&entity.User{}); err != nil {
+		if err := testDB.Migrator().DropTable(&entity.UserRelation{}, &entity.DataExport{}, &entity.EmailChange{}, &entity.User{}); err != nil {
 			panic(err)
 		}
 	}()
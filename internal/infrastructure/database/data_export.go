@@ -0,0 +1,95 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"chatapp/internal/domain/entity"
+
+	"gorm.io/gorm"
+)
+
+// staleProcessingTimeout is how long an export can stay processing before another worker takes it over
+const staleProcessingTimeout = 10 * time.Minute
+
+// DataExportRepository is a repository for the data export entity
+type DataExportRepository struct {
+	DB *gorm.DB
+}
+
+// NewDataExportRepository creates a new data export repository
+func NewDataExportRepository(db *gorm.DB) *DataExportRepository {
+	return &DataExportRepository{DB: db}
+}
+
+// Create creates a new data export
+func (r *DataExportRepository) Create(export *entity.DataExport) (*entity.DataExport, error) {
+	if err := r.DB.Create(export).Error; err != nil {
+		return nil, fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	return export, nil
+}
+
+// FindByID finds a data export by ID
+func (r *DataExportRepository) FindByID(id string) (*entity.DataExport, error) {
+	var export entity.DataExport
+	err := r.DB.First(&export, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find data export by ID: %w", err)
+	}
+
+	return &export, nil
+}
+
+// ClaimPending marks the oldest pending export as processing and returns it,
+// or nil when there is none. Concurrent workers never claim the same export,
+// and exports left processing by a crashed worker are claimed again.
+func (r *DataExportRepository) ClaimPending() (*entity.DataExport, error) {
+	var exports []*entity.DataExport
+	err := r.DB.Raw(`
+		UPDATE data_exports SET status = ?, updated_at = ?
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE deleted_at IS NULL AND (status = ? OR (status = ? AND updated_at < ?))
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		entity.DataExportProcessing, time.Now(),
+		entity.DataExportPending, entity.DataExportProcessing, time.Now().Add(-staleProcessingTimeout),
+	).Scan(&exports).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending data export: %w", err)
+	}
+	if len(exports) == 0 {
+		return nil, nil
+	}
+
+	return exports[0], nil
+}
+
+// FindExpired finds the ready exports whose download period ended before now
+func (r *DataExportRepository) FindExpired(now time.Time) ([]*entity.DataExport, error) {
+	var exports []*entity.DataExport
+	err := r.DB.Where("status = ? AND expires_at <= ?", entity.DataExportReady, now).Find(&exports).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired data exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+// Update updates a data export
+func (r *DataExportRepository) Update(export *entity.DataExport) error {
+	if err := r.DB.Save(export).Error; err != nil {
+		return fmt.Errorf("failed to update data export: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"chatapp/internal/domain/entity"
+	helper "chatapp/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimPending(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    entity.DataExportStatus
+		updatedAt time.Time
+		wantFound bool
+	}{
+		{
+			name:      "success pending export",
+			status:    entity.DataExportPending,
+			updatedAt: time.Now(),
+			wantFound: true,
+		},
+		{
+			name:      "success stale processing export",
+			status:    entity.DataExportProcessing,
+			updatedAt: time.Now().Add(-staleProcessingTimeout - time.Minute),
+			wantFound: true,
+		},
+		{
+			name:      "not found processing export",
+			status:    entity.DataExportProcessing,
+			updatedAt: time.Now(),
+			wantFound: false,
+		},
+		{
+			name:      "not found ready export",
+			status:    entity.DataExportReady,
+			updatedAt: time.Now(),
+			wantFound: false,
+		},
+	}
+
+	for _, test := range tests {
+		// Create transaction
+		tx := testDB.Begin()
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+
+		t.Run(test.name, func(t *testing.T) {
+			var user entity.User
+			tx.Where("email = ?", "test@test.com").First(&user)
+			export, _, _ := entity.NewDataExport(user.ID)
+			export.Status = test.status
+			tx.Create(export)
+			tx.Model(export).UpdateColumn("updated_at", test.updatedAt)
+
+			repo := &DataExportRepository{DB: tx}
+			claimed, err := repo.ClaimPending()
+			assert.NoError(t, err)
+			if test.wantFound {
+				assert.NotNil(t, claimed)
+				assert.Equal(t, export.ID, claimed.ID)
+				assert.Equal(t, entity.DataExportProcessing, claimed.Status)
+
+				// A claimed export is not claimed again
+				again, err := repo.ClaimPending()
+				assert.NoError(t, err)
+				assert.Nil(t, again)
+			} else {
+				assert.Nil(t, claimed)
+			}
+		})
+		tx.Rollback()
+	}
+}
+
+func TestFindExpired(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    entity.DataExportStatus
+		expiresAt time.Time
+		wantFound bool
+	}{
+		{
+			name:      "success expired export",
+			status:    entity.DataExportReady,
+			expiresAt: time.Now().Add(-time.Hour),
+			wantFound: true,
+		},
+		{
+			name:      "not found unexpired export",
+			status:    entity.DataExportReady,
+			expiresAt: time.Now().Add(time.Hour),
+			wantFound: false,
+		},
+		{
+			name:      "not found already expired export",
+			status:    entity.DataExportExpired,
+			expiresAt: time.Now().Add(-time.Hour),
+			wantFound: false,
+		},
+	}
+
+	for _, test := range tests {
+		// Create transaction
+		tx := testDB.Begin()
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+
+		t.Run(test.name, func(t *testing.T) {
+			var user entity.User
+			tx.Where("email = ?", "test@test.com").First(&user)
+			export, _, _ := entity.NewDataExport(user.ID)
+			export.Status = test.status
+			export.ExpiresAt = &test.expiresAt
+			tx.Create(export)
+
+			repo := &DataExportRepository{DB: tx}
+			exports, err := repo.FindExpired(time.Now())
+			assert.NoError(t, err)
+			if test.wantFound {
+				assert.Equal(t, 1, len(exports))
+				assert.Equal(t, export.ID, exports[0].ID)
+			} else {
+				assert.Empty(t, exports)
+			}
+		})
+		tx.Rollback()
+	}
+}
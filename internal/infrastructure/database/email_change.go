@@ -0,0 +1,132 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"chatapp/internal/domain/entity"
+
+	"gorm.io/gorm"
+)
+
+// EmailChangeRepository is a repository for the email change entity
+type EmailChangeRepository struct {
+	DB *gorm.DB
+}
+
+// NewEmailChangeRepository creates a new email change repository
+func NewEmailChangeRepository(db *gorm.DB) *EmailChangeRepository {
+	return &EmailChangeRepository{DB: db}
+}
+
+// Create creates a new email change and cancels the other pending changes of the user
+func (r *EmailChangeRepository) Create(change *entity.EmailChange) (*entity.EmailChange, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND confirmed_at IS NULL", change.UserID).Delete(&entity.EmailChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create email change: %w", err)
+	}
+
+	return change, nil
+}
+
+// FindByConfirmTokenHash finds an email change by the hash of its confirmation token
+func (r *EmailChangeRepository) FindByConfirmTokenHash(tokenHash string) (*entity.EmailChange, error) {
+	var change entity.EmailChange
+	err := r.DB.Where("confirm_token_hash = ?", tokenHash).First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find email change by confirm token: %w", err)
+	}
+
+	return &change, nil
+}
+
+// FindByRevertTokenHash finds an email change by the hash of its revert token
+func (r *EmailChangeRepository) FindByRevertTokenHash(tokenHash string) (*entity.EmailChange, error) {
+	var change entity.EmailChange
+	err := r.DB.Where("revert_token_hash = ?", tokenHash).First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find email change by revert token: %w", err)
+	}
+
+	return &change, nil
+}
+
+// FindByUserID finds every email change of a user, oldest first,
+// including the pending requests cancelled by a newer one
+func (r *EmailChangeRepository) FindByUserID(userID uint) ([]*entity.EmailChange, error) {
+	var changes []*entity.EmailChange
+	err := r.DB.Unscoped().Where("user_id = ?", userID).Order("id").Find(&changes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find email changes by user ID: %w", err)
+	}
+
+	return changes, nil
+}
+
+// FindRevertableByOldEmail finds a confirmed email change away from the email that can still be reverted
+func (r *EmailChangeRepository) FindRevertableByOldEmail(email string, now time.Time) (*entity.EmailChange, error) {
+	var change entity.EmailChange
+	err := r.DB.
+		Where("old_email = ? AND confirmed_at IS NOT NULL AND reverted_at IS NULL AND revert_expires_at > ?", email, now).
+		Order("id DESC").
+		First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find email change by old email: %w", err)
+	}
+
+	return &change, nil
+}
+
+// ConfirmWithUser saves a confirmed email change and the new email of its user in one transaction.
+// It fails with entity.ErrStaleUpdate when a concurrent request confirmed the change first.
+func (r *EmailChangeRepository) ConfirmWithUser(change *entity.EmailChange, user *entity.User) error {
+	if err := r.updateWithUser(change, user, "confirmed_at IS NULL"); err != nil {
+		return fmt.Errorf("failed to confirm email change: %w", err)
+	}
+
+	return nil
+}
+
+// RevertWithUser saves a reverted email change and the old email of its user in one transaction.
+// It fails with entity.ErrStaleUpdate when a concurrent request reverted the change first.
+func (r *EmailChangeRepository) RevertWithUser(change *entity.EmailChange, user *entity.User) error {
+	if err := r.updateWithUser(change, user, "reverted_at IS NULL"); err != nil {
+		return fmt.Errorf("failed to revert email change: %w", err)
+	}
+
+	return nil
+}
+
+// updateWithUser saves the change only if its row still matches the condition, so that
+// of concurrent requests reading the same change only the first one to write applies
+func (r *EmailChangeRepository) updateWithUser(change *entity.EmailChange, user *entity.User, condition string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(change).
+			Where(condition).
+			Select("ConfirmedAt", "RevertTokenHash", "RevertExpiresAt", "RevertedAt").
+			Updates(change)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrStaleUpdate
+		}
+
+		return translateError(tx.Save(user).Error)
+	})
+}
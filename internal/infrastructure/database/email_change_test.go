@@ -0,0 +1,230 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"chatapp/internal/domain/entity"
+	helper "chatapp/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateEmailChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			wantErr: false,
+		},
+	}
+
+	for _, test := range tests {
+		// Create transaction
+		tx := testDB.Begin()
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+
+		t.Run(test.name, func(t *testing.T) {
+			var user entity.User
+			tx.Where("email = ?", "test@test.com").First(&user)
+			first, _, _ := entity.NewEmailChange(&user, "first@test.com", time.Now())
+			second, _, _ := entity.NewEmailChange(&user, "second@test.com", time.Now())
+
+			repo := &EmailChangeRepository{DB: tx}
+			_, err := repo.Create(first)
+			assert.NoError(t, err)
+			created, err := repo.Create(second)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.NotZero(t, created.ID)
+
+				// Only the latest request stays pending
+				var pending []entity.EmailChange
+				tx.Where("user_id = ?", user.ID).Find(&pending)
+				assert.Equal(t, 1, len(pending))
+				assert.Equal(t, "second@test.com", pending[0].NewEmail)
+			}
+		})
+		tx.Rollback()
+	}
+}
+
+func TestFindByUserID(t *testing.T) {
+	// Create transaction
+	tx := testDB.Begin()
+	defer tx.Rollback()
+	helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+	var user entity.User
+	tx.Where("email = ?", "test@test.com").First(&user)
+	first, _, _ := entity.NewEmailChange(&user, "first@test.com", time.Now())
+	second, _, _ := entity.NewEmailChange(&user, "second@test.com", time.Now())
+
+	repo := &EmailChangeRepository{DB: tx}
+	repo.Create(first)
+	repo.Create(second)
+
+	// The first request was cancelled by the second one but is still held
+	changes, err := repo.FindByUserID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(changes))
+	assert.Equal(t, "first@test.com", changes[0].NewEmail)
+	assert.True(t, changes[0].DeletedAt.Valid)
+	assert.Equal(t, "second@test.com", changes[1].NewEmail)
+	assert.False(t, changes[1].DeletedAt.Valid)
+}
+
+func TestFindRevertableByOldEmail(t *testing.T) {
+	// Create transaction
+	tx := testDB.Begin()
+	defer tx.Rollback()
+	helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+	var user entity.User
+	tx.Where("email = ?", "test@test.com").First(&user)
+
+	repo := &EmailChangeRepository{DB: tx}
+	change, _, _ := entity.NewEmailChange(&user, "new@test.com", time.Now())
+	repo.Create(change)
+
+	// A pending change does not hold the old address yet
+	found, err := repo.FindRevertableByOldEmail("test@test.com", time.Now())
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	change.Confirm(&user, time.Now())
+	assert.NoError(t, repo.ConfirmWithUser(change, &user))
+
+	found, err = repo.FindRevertableByOldEmail("test@test.com", time.Now())
+	assert.NoError(t, err)
+	assert.NotNil(t, found)
+	assert.Equal(t, change.ID, found.ID)
+
+	found, err = repo.FindRevertableByOldEmail("test@test.com", time.Now().Add(entity.EmailChangeRevertTTL+time.Hour))
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestFindByConfirmTokenHash(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenHash func(string) string
+		wantFound bool
+	}{
+		{
+			name:      "success",
+			tokenHash: entity.HashToken,
+			wantFound: true,
+		},
+		{
+			name:      "not found",
+			tokenHash: func(string) string { return entity.HashToken("unknown") },
+			wantFound: false,
+		},
+	}
+
+	for _, test := range tests {
+		// Create transaction
+		tx := testDB.Begin()
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+
+		t.Run(test.name, func(t *testing.T) {
+			var user entity.User
+			tx.Where("email = ?", "test@test.com").First(&user)
+			change, token, _ := entity.NewEmailChange(&user, "new@test.com", time.Now())
+			tx.Create(change)
+
+			repo := &EmailChangeRepository{DB: tx}
+			found, err := repo.FindByConfirmTokenHash(test.tokenHash(token))
+			assert.NoError(t, err)
+			if test.wantFound {
+				assert.Equal(t, change.ID, found.ID)
+			} else {
+				assert.Nil(t, found)
+			}
+		})
+		tx.Rollback()
+	}
+}
+
+func TestConfirmWithUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		newEmail string
+		wantErr  error
+	}{
+		{
+			name:     "success",
+			newEmail: "new@test.com",
+			wantErr:  nil,
+		},
+		{
+			name:     "error duplicated email",
+			newEmail: "taken@test.com",
+			wantErr:  entity.ErrAlreadyExists,
+		},
+	}
+
+	for _, test := range tests {
+		// Create transaction
+		tx := testDB.Begin()
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+		helper.CreateTestUser(tx, "taken", "taken", "taken@test.com", "password")
+
+		t.Run(test.name, func(t *testing.T) {
+			var user entity.User
+			tx.Where("email = ?", "test@test.com").First(&user)
+			change, _, _ := entity.NewEmailChange(&user, test.newEmail, time.Now())
+			tx.Create(change)
+			revertToken, _ := change.Confirm(&user, time.Now())
+
+			repo := &EmailChangeRepository{DB: tx}
+			err := repo.ConfirmWithUser(change, &user)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+				found, _ := repo.FindByRevertTokenHash(entity.HashToken(revertToken))
+				assert.Equal(t, change.ID, found.ID)
+				var updatedUser entity.User
+				tx.First(&updatedUser, user.ID)
+				assert.Equal(t, test.newEmail, updatedUser.Email)
+			}
+		})
+		tx.Rollback()
+	}
+}
+
+func TestConfirmWithUserConcurrently(t *testing.T) {
+	// Create transaction
+	tx := testDB.Begin()
+	defer tx.Rollback()
+	helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+	var user entity.User
+	tx.Where("email = ?", "test@test.com").First(&user)
+	change, _, _ := entity.NewEmailChange(&user, "new@test.com", time.Now())
+	tx.Create(change)
+
+	// Two requests read the same pending change and both confirm it
+	first, second := *change, *change
+	firstUser, secondUser := user, user
+	firstToken, _ := first.Confirm(&firstUser, time.Now())
+	_, _ = second.Confirm(&secondUser, time.Now())
+
+	repo := &EmailChangeRepository{DB: tx}
+	assert.NoError(t, repo.ConfirmWithUser(&first, &firstUser))
+	assert.ErrorIs(t, repo.ConfirmWithUser(&second, &secondUser), entity.ErrStaleUpdate)
+
+	// The revert link of the request that won is the one stored
+	found, _ := repo.FindByRevertTokenHash(entity.HashToken(firstToken))
+	assert.NotNil(t, found)
+
+	// Reverting twice only applies once
+	firstRevert, secondRevert := *found, *found
+	assert.NoError(t, firstRevert.Revert(&firstUser, time.Now()))
+	assert.NoError(t, secondRevert.Revert(&secondUser, time.Now()))
+	assert.NoError(t, repo.RevertWithUser(&firstRevert, &firstUser))
+	assert.ErrorIs(t, repo.RevertWithUser(&secondRevert, &secondUser), entity.ErrStaleUpdate)
+}
@@ -43,7 +43,8 @@ func NewPostgresConfig(host, user, password, dbName, port, sslMode string) (*Pos
 
 // NewPostgresDB creates a new postgres database connection
 func NewPostgresDB(dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError turns unique violations into gorm.ErrDuplicatedKey
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -59,6 +60,90 @@ func (c *PostgresConfig) NewDSN() string {
 	)
 }
 
+// legacyConstraints are unique constraints replaced by partial unique indexes
+// that ignore soft-deleted rows, AutoMigrate does not drop them by itself
+var legacyConstraints = map[string][]string{
+	"users": {"users_email_key", "users_handle_key"},
+}
+
 func Migrate(db *gorm.DB, entities ...interface{}) error {
-	return db.AutoMigrate(entities...)
+	if err := addUserHandle(db); err != nil {
+		return err
+	}
+	if err := dropPartialUserIndexes(db); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(entities...); err != nil {
+		return err
+	}
+
+	for table, constraints := range legacyConstraints {
+		if !db.Migrator().HasTable(table) {
+			continue
+		}
+		for _, constraint := range constraints {
+			if err := db.Exec(fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", table, constraint)).Error; err != nil {
+				return fmt.Errorf("failed to drop constraint %s: %w", constraint, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// partialUserIndexes are the unique indexes on users that ignored soft-deleted rows.
+// They let a deleted user's email or handle be registered again before the user was purged,
+// after which the user could not be restored.
+var partialUserIndexes = []string{"idx_users_email", "idx_users_handle"}
+
+// dropPartialUserIndexes drops the partial unique indexes so that AutoMigrate creates unique
+// indexes over every row. Soft-deleted users whose email or handle was registered again can never
+// be restored, they are purged first or the new indexes could not be created.
+func dropPartialUserIndexes(db *gorm.DB) error {
+	if !db.Migrator().HasTable("users") || !db.Migrator().HasIndex("users", partialUserIndexes[0]) {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		taken := `EXISTS (
+			SELECT 1 FROM users other
+			WHERE other.id <> users.id
+			AND (other.email = users.email OR other.handle = users.handle)
+			AND (other.deleted_at IS NULL OR other.deleted_at > users.deleted_at)
+		)`
+		if _, err := purgeUsers(tx, taken); err != nil {
+			return fmt.Errorf("failed to purge users that cannot be restored: %w", err)
+		}
+
+		for _, index := range partialUserIndexes {
+			if err := tx.Exec(fmt.Sprintf("DROP INDEX IF EXISTS %s", index)).Error; err != nil {
+				return fmt.Errorf("failed to drop index %s: %w", index, err)
+			}
+		}
+		return nil
+	})
+}
+
+// addUserHandle adds the handle column to a users table created before handles existed.
+// AutoMigrate would add it as NOT NULL right away, which Postgres rejects on a table with rows,
+// so existing users get a unique handle from their ID before the constraint is set.
+func addUserHandle(db *gorm.DB) error {
+	if !db.Migrator().HasTable("users") || db.Migrator().HasColumn("users", "handle") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"ALTER TABLE users ADD COLUMN handle varchar(30)",
+			"UPDATE users SET handle = 'user_' || id WHERE handle IS NULL",
+			"ALTER TABLE users ALTER COLUMN handle SET NOT NULL",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to add user handles: %w", err)
+			}
+		}
+		return nil
+	})
 }
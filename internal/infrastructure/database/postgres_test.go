@@ -0,0 +1,64 @@
+package database
+
+import (
+	"testing"
+
+	"chatapp/internal/domain/entity"
+	helper "chatapp/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddUserHandle(t *testing.T) {
+	// Create transaction, DDL is rolled back with it in Postgres
+	tx := testDB.Begin()
+	defer tx.Rollback()
+	helper.CreateTestUser(tx, "first", "first", "first@test.com", "password")
+	helper.CreateTestUser(tx, "second", "second", "second@test.com", "password")
+
+	// Go back to a users table created before handles existed
+	assert.NoError(t, tx.Exec("ALTER TABLE users DROP COLUMN handle").Error)
+
+	assert.NoError(t, addUserHandle(tx))
+	assert.True(t, tx.Migrator().HasColumn("users", "handle"))
+
+	var users []entity.User
+	tx.Order("id").Find(&users)
+	assert.Equal(t, 2, len(users))
+	for _, user := range users {
+		assert.NoError(t, entity.ValidateHandle(user.Handle))
+	}
+	assert.NotEqual(t, users[0].Handle, users[1].Handle)
+
+	// Running again once the column exists does nothing
+	assert.NoError(t, addUserHandle(tx))
+}
+
+func TestDropPartialUserIndexes(t *testing.T) {
+	// Create transaction, DDL is rolled back with it in Postgres
+	tx := testDB.Begin()
+	defer tx.Rollback()
+
+	// Go back to the partial indexes, under which a deleted user's email could be registered again
+	assert.NoError(t, tx.Exec("DROP INDEX idx_users_unique_email, idx_users_unique_handle").Error)
+	assert.NoError(t, tx.Exec("CREATE UNIQUE INDEX idx_users_email ON users (email) WHERE deleted_at IS NULL").Error)
+	assert.NoError(t, tx.Exec("CREATE UNIQUE INDEX idx_users_handle ON users (handle) WHERE deleted_at IS NULL").Error)
+	helper.CreateTestUser(tx, "taken", "taken", "taken@test.com", "password")
+	tx.Where("email = ?", "taken@test.com").Delete(&entity.User{})
+	helper.CreateTestUser(tx, "new", "new", "taken@test.com", "password")
+	helper.CreateTestUser(tx, "deleted", "deleted", "deleted@test.com", "password")
+	tx.Where("email = ?", "deleted@test.com").Delete(&entity.User{})
+
+	assert.NoError(t, dropPartialUserIndexes(tx))
+	assert.False(t, tx.Migrator().HasIndex("users", "idx_users_email"))
+	assert.NoError(t, tx.AutoMigrate(&entity.User{}))
+	assert.True(t, tx.Migrator().HasIndex(&entity.User{}, "idx_users_unique_email"))
+
+	// Only the deleted user whose email was taken again, and who could never be restored, is purged
+	var handles []string
+	tx.Unscoped().Model(&entity.User{}).Order("handle").Pluck("handle", &handles)
+	assert.Equal(t, []string{"deleted", "new"}, handles)
+
+	// Running again once the indexes are gone does nothing
+	assert.NoError(t, dropPartialUserIndexes(tx))
+}
@@ -3,6 +3,8 @@ package database
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"chatapp/internal/domain/entity"
 
@@ -23,7 +25,7 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 func (r *UserRepository) Create(user *entity.User) (*entity.User, error) {
 	result := r.DB.Create(user)
 	if result.Error != nil {
-		return nil, fmt.Errorf("failed to create user: %w", result.Error)
+		return nil, fmt.Errorf("failed to create user: %w", translateError(result.Error))
 	}
 
 	return user, nil
@@ -43,6 +45,20 @@ func (r *UserRepository) FindByEmail(email string) (*entity.User, error) {
 	return &user, nil
 }
 
+// FindByHandle finds a user by handle
+func (r *UserRepository) FindByHandle(handle string) (*entity.User, error) {
+	var user entity.User
+	err := r.DB.Where("handle = ?", handle).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user by handle: %w", err)
+	}
+
+	return &user, nil
+}
+
 // FindByID finds a user by ID
 func (r *UserRepository) FindByID(id string) (*entity.User, error) {
 	var user entity.User
@@ -57,15 +73,56 @@ func (r *UserRepository) FindByID(id string) (*entity.User, error) {
 	return &user, nil
 }
 
-// FindAll finds all users
-func (r *UserRepository) FindAll() ([]*entity.User, error) {
-	var users []*entity.User
-	err := r.DB.Find(&users).Error
+// FindDeletedByEmail finds the most recently deleted user with the email
+func (r *UserRepository) FindDeletedByEmail(email string) (*entity.User, error) {
+	var user entity.User
+	err := r.DB.Unscoped().
+		Where("email = ? AND deleted_at IS NOT NULL", email).
+		Order("deleted_at DESC").
+		First(&user).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to find all users: %w", err)
+		return nil, fmt.Errorf("failed to find deleted user by email: %w", err)
+	}
+
+	return &user, nil
+}
+
+// FindPage finds one page of users matching the query, ordered by the sort field and ID
+func (r *UserRepository) FindPage(query *entity.UserListQuery) ([]*entity.User, error) {
+	column := string(query.SortBy)
+	direction, comparison := "ASC", ">"
+	if query.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	tx := r.DB.Model(&entity.User{})
+	if query.Prefix != "" {
+		pattern := escapeLike(query.Prefix) + "%"
+		tx = tx.Where("name ILIKE ? OR handle ILIKE ?", pattern, pattern)
+	}
+
+	if query.SortBy == entity.UserSortByID {
+		if query.After != nil {
+			tx = tx.Where(fmt.Sprintf("id %s ?", comparison), query.After.ID)
+		}
+		tx = tx.Order(fmt.Sprintf("id %s", direction))
+	} else {
+		if query.After != nil {
+			value, err := cursorValue(query.SortBy, query.After.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find users: %w", err)
+			}
+			tx = tx.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison), value, query.After.ID)
+		}
+		tx = tx.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction))
+	}
+
+	var users []*entity.User
+	if err := tx.Limit(query.Limit).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
 	}
 
 	return users, nil
@@ -74,7 +131,7 @@ func (r *UserRepository) FindAll() ([]*entity.User, error) {
 // Update updates a user
 func (r *UserRepository) Update(user *entity.User) error {
 	if err := r.DB.Save(user).Error; err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
+		return fmt.Errorf("failed to update user: %w", translateError(err))
 	}
 
 	return nil
@@ -88,3 +145,82 @@ func (r *UserRepository) Delete(user *entity.User) error {
 
 	return nil
 }
+
+// Restore restores a soft-deleted user
+func (r *UserRepository) Restore(user *entity.User) error {
+	if err := r.DB.Unscoped().Model(user).Update("deleted_at", nil).Error; err != nil {
+		return fmt.Errorf("failed to restore user: %w", translateError(err))
+	}
+
+	return nil
+}
+
+// PurgeDeletedBefore permanently deletes the users soft-deleted before the cutoff,
+// with their email changes, data exports and the blocks and mutes set by or on them
+func (r *UserRepository) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	var purged int64
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		purged, err = purgeUsers(tx, "deleted_at < ?", cutoff)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+
+	return purged, nil
+}
+
+// purgeUsers permanently deletes the soft-deleted users matching the condition and their records.
+// Tables of later features are skipped while they do not exist yet, as when migrating.
+func purgeUsers(tx *gorm.DB, condition string, args ...interface{}) (int64, error) {
+	purgedIDs := tx.Unscoped().Model(&entity.User{}).Select("id").Where("deleted_at IS NOT NULL").Where(condition, args...)
+	for _, owned := range []interface{}{&entity.EmailChange{}, &entity.DataExport{}} {
+		if !tx.Migrator().HasTable(owned) {
+			continue
+		}
+		if err := tx.Unscoped().Where("user_id IN (?)", purgedIDs).Delete(owned).Error; err != nil {
+			return 0, err
+		}
+	}
+	if tx.Migrator().HasTable(&entity.UserRelation{}) {
+		err := tx.Unscoped().
+			Where("user_id IN (?) OR target_id IN (?)", purgedIDs, purgedIDs).
+			Delete(&entity.UserRelation{}).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	result := tx.Unscoped().Where("deleted_at IS NOT NULL").Where(condition, args...).Delete(&entity.User{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// translateError maps database errors to the domain errors the use cases check for
+func translateError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return entity.ErrAlreadyExists
+	}
+	return err
+}
+
+// cursorValue converts a cursor value back to the type of its sort column
+func cursorValue(sortBy entity.UserSortField, value string) (interface{}, error) {
+	if sortBy != entity.UserSortByCreatedAt {
+		return value, nil
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor time: %w", err)
+	}
+	return createdAt, nil
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"fmt"
+
+	"chatapp/internal/domain/entity"
+
+	"gorm.io/gorm"
+)
+
+// UserRelationRepository is a repository for the user relation entity
+type UserRelationRepository struct {
+	DB *gorm.DB
+}
+
+// NewUserRelationRepository creates a new user relation repository
+func NewUserRelationRepository(db *gorm.DB) *UserRelationRepository {
+	return &UserRelationRepository{DB: db}
+}
+
+// Create creates a new user relation
+func (r *UserRelationRepository) Create(relation *entity.UserRelation) (*entity.UserRelation, error) {
+	if err := r.DB.Create(relation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user relation: %w", translateError(err))
+	}
+
+	return relation, nil
+}
+
+// Delete permanently deletes the relation of the kind from the user to the target, if any.
+// Relations are not soft-deleted so that they can be set again without hitting the unique index.
+func (r *UserRelationRepository) Delete(userID, targetID uint, kind entity.UserRelationKind) error {
+	err := r.DB.Unscoped().
+		Where("user_id = ? AND target_id = ? AND kind = ?", userID, targetID, kind).
+		Delete(&entity.UserRelation{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete user relation: %w", err)
+	}
+
+	return nil
+}
+
+// FindTargets finds the users the user has a relation of the kind with, in the order they were added
+func (r *UserRelationRepository) FindTargets(userID uint, kind entity.UserRelationKind) ([]*entity.User, error) {
+	var users []*entity.User
+	err := r.DB.
+		Joins("JOIN user_relations ON user_relations.target_id = users.id AND user_relations.deleted_at IS NULL").
+		Where("user_relations.user_id = ? AND user_relations.kind = ?", userID, kind).
+		Order("user_relations.id").
+		Find(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user relation targets: %w", err)
+	}
+
+	return users, nil
+}
+
+// FindByUserID finds every block and mute set by the user, in the order they were added
+func (r *UserRelationRepository) FindByUserID(userID uint) ([]*entity.UserRelation, error) {
+	var relations []*entity.UserRelation
+	err := r.DB.Where("user_id = ?", userID).Order("id").Find(&relations).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user relations by user ID: %w", err)
+	}
+
+	return relations, nil
+}
+
+// Exists reports whether the user has a relation of the kind with the target
+func (r *UserRelationRepository) Exists(userID, targetID uint, kind entity.UserRelationKind) (bool, error) {
+	var count int64
+	err := r.DB.Model(&entity.UserRelation{}).
+		Where("user_id = ? AND target_id = ? AND kind = ?", userID, targetID, kind).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check user relation: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// ExistsBetween reports whether either user has a relation of the kind with the other
+func (r *UserRelationRepository) ExistsBetween(userID, otherID uint, kind entity.UserRelationKind) (bool, error) {
+	var count int64
+	err := r.DB.Model(&entity.UserRelation{}).
+		Where("kind = ? AND ((user_id = ? AND target_id = ?) OR (user_id = ? AND target_id = ?))", kind, userID, otherID, otherID, userID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check user relation: %w", err)
+	}
+
+	return count > 0, nil
+}
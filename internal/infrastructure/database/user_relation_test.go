@@ -0,0 +1,103 @@
+package database
+
+import (
+	"testing"
+
+	"chatapp/internal/domain/entity"
+	helper "chatapp/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateUserRelation(t *testing.T) {
+	tests := []struct {
+		name    string
+		kinds   []entity.UserRelationKind
+		wantErr error
+	}{
+		{
+			name:    "success block and mute the same user",
+			kinds:   []entity.UserRelationKind{entity.UserRelationBlock, entity.UserRelationMute},
+			wantErr: nil,
+		},
+		{
+			name:    "error already blocked",
+			kinds:   []entity.UserRelationKind{entity.UserRelationBlock, entity.UserRelationBlock},
+			wantErr: entity.ErrAlreadyExists,
+		},
+	}
+
+	for _, test := range tests {
+		// Create transaction
+		tx := testDB.Begin()
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+		helper.CreateTestUser(tx, "other", "other", "other@test.com", "password")
+
+		t.Run(test.name, func(t *testing.T) {
+			var user, other entity.User
+			tx.Where("email = ?", "test@test.com").First(&user)
+			tx.Where("email = ?", "other@test.com").First(&other)
+
+			repo := &UserRelationRepository{DB: tx}
+			var err error
+			for _, kind := range test.kinds {
+				relation, _ := entity.NewUserRelation(user.ID, other.ID, kind)
+				_, err = repo.Create(relation)
+			}
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+		tx.Rollback()
+	}
+}
+
+func TestUserRelationLifecycle(t *testing.T) {
+	// Create transaction
+	tx := testDB.Begin()
+	defer tx.Rollback()
+	helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+	helper.CreateTestUser(tx, "other", "other", "other@test.com", "password")
+	var user, other entity.User
+	tx.Where("email = ?", "test@test.com").First(&user)
+	tx.Where("email = ?", "other@test.com").First(&other)
+
+	repo := &UserRelationRepository{DB: tx}
+	relation, _ := entity.NewUserRelation(user.ID, other.ID, entity.UserRelationBlock)
+	_, err := repo.Create(relation)
+	assert.NoError(t, err)
+
+	targets, err := repo.FindTargets(user.ID, entity.UserRelationBlock)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(targets))
+	assert.Equal(t, other.ID, targets[0].ID)
+
+	muted, err := repo.FindTargets(user.ID, entity.UserRelationMute)
+	assert.NoError(t, err)
+	assert.Empty(t, muted)
+
+	relations, err := repo.FindByUserID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(relations))
+	assert.Equal(t, entity.UserRelationBlock, relations[0].Kind)
+
+	// A block has a direction but is seen from both sides
+	exists, err := repo.Exists(other.ID, user.ID, entity.UserRelationBlock)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	blocked, err := repo.ExistsBetween(other.ID, user.ID, entity.UserRelationBlock)
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+
+	assert.NoError(t, repo.Delete(user.ID, other.ID, entity.UserRelationBlock))
+	blocked, err = repo.ExistsBetween(user.ID, other.ID, entity.UserRelationBlock)
+	assert.NoError(t, err)
+	assert.False(t, blocked)
+
+	// It can be set again once removed
+	relation, _ = entity.NewUserRelation(user.ID, other.ID, entity.UserRelationBlock)
+	_, err = repo.Create(relation)
+	assert.NoError(t, err)
+}
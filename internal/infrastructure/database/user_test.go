@@ -1,7 +1,10 @@
 package database
 
 import (
+	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"chatapp/internal/domain/entity"
 	helper "chatapp/tests"
@@ -19,6 +22,7 @@ func TestCreate(t *testing.T) {
 			name: "success",
 			input: &entity.User{
 				Name:     "test",
+				Handle:   "test",
 				Email:    "test@test.com",
 				Password: "password",
 			},
@@ -28,6 +32,7 @@ func TestCreate(t *testing.T) {
 			name: "error empty name",
 			input: &entity.User{
 				Name:     "",
+				Handle:   "test2",
 				Email:    "test2@test.com",
 				Password: "password",
 			},
@@ -37,6 +42,7 @@ func TestCreate(t *testing.T) {
 			name: "error empty email",
 			input: &entity.User{
 				Name:     "test",
+				Handle:   "test",
 				Email:    "",
 				Password: "password",
 			},
@@ -46,15 +52,27 @@ func TestCreate(t *testing.T) {
 			name: "error duplicated email",
 			input: &entity.User{
 				Name:     "test",
+				Handle:   "test",
 				Email:    "initial@test.com",
 				Password: "password",
 			},
 			wantErr: true,
 		},
+		{
+			name: "error duplicated handle",
+			input: &entity.User{
+				Name:     "test",
+				Handle:   "initial",
+				Email:    "test4@test.com",
+				Password: "password",
+			},
+			wantErr: true,
+		},
 		{
 			name: "error empty password",
 			input: &entity.User{
 				Name:     "test",
+				Handle:   "test",
 				Email:    "test3@test.com",
 				Password: "",
 			},
@@ -65,13 +83,16 @@ func TestCreate(t *testing.T) {
 	for _, test := range tests {
 		// Create transaction
 		tx := testDB.Begin()
-		helper.CreateTestUser(tx, "initial", "initial@test.com", "password")
+		helper.CreateTestUser(tx, "initial", "initial", "initial@test.com", "password")
 
 		t.Run(test.name, func(t *testing.T) {
 			repo := &UserRepository{DB: tx}
 			user, err := repo.Create(test.input)
 			if test.wantErr {
 				assert.Error(t, err)
+				if strings.HasPrefix(test.name, "error duplicated") {
+					assert.ErrorIs(t, err, entity.ErrAlreadyExists)
+				}
 			} else {
 				assert.NoError(t, err)
 				assert.NotZero(t, user.ID)
@@ -109,7 +130,7 @@ func TestFindByEmail(t *testing.T) {
 	for _, test := range tests {
 		// Create transaction
 		tx := testDB.Begin()
-		helper.CreateTestUser(tx, "test", "test@test.com", "password")
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
 
 		t.Run(test.name, func(t *testing.T) {
 			repo := &UserRepository{DB: tx}
@@ -128,6 +149,46 @@ func TestFindByEmail(t *testing.T) {
 	}
 }
 
+func TestFindByHandle(t *testing.T) {
+	tests := []struct {
+		name        string
+		inputHandle string
+		wantErr     bool
+	}{
+		{
+			name:        "success",
+			inputHandle: "test",
+			wantErr:     false,
+		},
+		{
+			name:        "not found",
+			inputHandle: "not_found",
+			wantErr:     false,
+		},
+	}
+
+	for _, test := range tests {
+		// Create transaction
+		tx := testDB.Begin()
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+
+		t.Run(test.name, func(t *testing.T) {
+			repo := &UserRepository{DB: tx}
+			user, err := repo.FindByHandle(test.inputHandle)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else if user == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "test", user.Name)
+				assert.Equal(t, "test", user.Handle)
+			}
+		})
+		tx.Rollback()
+	}
+}
+
 func TestFindByID(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -149,7 +210,7 @@ func TestFindByID(t *testing.T) {
 	for _, test := range tests {
 		// Create transaction
 		tx := testDB.Begin()
-		helper.CreateTestUser(tx, "test", "test@test.com", "password")
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
 
 		t.Run(test.name, func(t *testing.T) {
 			repo := &UserRepository{DB: tx}
@@ -168,41 +229,83 @@ func TestFindByID(t *testing.T) {
 	}
 }
 
-func TestFindAll(t *testing.T) {
+func TestFindPage(t *testing.T) {
 	tests := []struct {
-		name    string
-		wantErr bool
+		name      string
+		query     *entity.UserListQuery
+		wantNames []string
+		wantErr   bool
 	}{
 		{
-			name:    "success",
-			wantErr: false,
+			name:      "success",
+			query:     &entity.UserListQuery{SortBy: entity.UserSortByID, Limit: 10},
+			wantNames: []string{"alice", "bob", "alfred"},
+			wantErr:   false,
 		},
 		{
-			name:    "not found",
-			wantErr: false,
+			name:      "success with limit",
+			query:     &entity.UserListQuery{SortBy: entity.UserSortByID, Limit: 2},
+			wantNames: []string{"alice", "bob"},
+			wantErr:   false,
+		},
+		{
+			name:      "success with prefix",
+			query:     &entity.UserListQuery{Prefix: "AL", SortBy: entity.UserSortByName, Limit: 10},
+			wantNames: []string{"alfred", "alice"},
+			wantErr:   false,
+		},
+		{
+			name:      "success with wildcard prefix",
+			query:     &entity.UserListQuery{Prefix: "%", SortBy: entity.UserSortByName, Limit: 10},
+			wantNames: []string{},
+			wantErr:   false,
+		},
+		{
+			name:      "success with descending order",
+			query:     &entity.UserListQuery{SortBy: entity.UserSortByName, Desc: true, Limit: 10},
+			wantNames: []string{"bob", "alice", "alfred"},
+			wantErr:   false,
+		},
+		{
+			name: "success with cursor",
+			query: &entity.UserListQuery{
+				SortBy: entity.UserSortByHandle,
+				After:  &entity.UserCursor{Value: "alfred", ID: math.MaxInt32},
+				Limit:  10,
+			},
+			wantNames: []string{"alice", "bob"},
+			wantErr:   false,
+		},
+		{
+			name: "error invalid cursor time",
+			query: &entity.UserListQuery{
+				SortBy: entity.UserSortByCreatedAt,
+				After:  &entity.UserCursor{Value: "invalid"},
+				Limit:  10,
+			},
+			wantErr: true,
 		},
 	}
 
 	for _, test := range tests {
 		// Create transaction
 		tx := testDB.Begin()
-		helper.CreateTestUser(tx, "test", "test@test.com", "password")
-		helper.CreateTestUser(tx, "test2", "test2@test.com", "password")
+		helper.CreateTestUser(tx, "alice", "alice", "alice@test.com", "password")
+		helper.CreateTestUser(tx, "bob", "bob", "bob@test.com", "password")
+		helper.CreateTestUser(tx, "alfred", "alfred", "alfred@test.com", "password")
 
 		t.Run(test.name, func(t *testing.T) {
 			repo := &UserRepository{DB: tx}
-			users, err := repo.FindAll()
+			users, err := repo.FindPage(test.query)
 			if test.wantErr {
 				assert.Error(t, err)
-			} else if users == nil {
-				assert.NoError(t, err)
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, 2, len(users))
-				assert.Equal(t, "test", users[0].Name)
-				assert.Equal(t, "test@test.com", users[0].Email)
-				assert.Equal(t, "test2", users[1].Name)
-				assert.Equal(t, "test2@test.com", users[1].Email)
+				names := make([]string, len(users))
+				for i, user := range users {
+					names[i] = user.Name
+				}
+				assert.Equal(t, test.wantNames, names)
 			}
 		})
 		tx.Rollback()
@@ -223,7 +326,7 @@ func TestUpdate(t *testing.T) {
 	for _, test := range tests {
 		// Create transaction
 		tx := testDB.Begin()
-		helper.CreateTestUser(tx, "test", "test@test.com", "password")
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
 
 		t.Run(test.name, func(t *testing.T) {
 			var targetUser entity.User
@@ -261,7 +364,7 @@ func TestDelete(t *testing.T) {
 	for _, test := range tests {
 		// Create transaction
 		tx := testDB.Begin()
-		helper.CreateTestUser(tx, "test", "test@test.com", "password")
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
 
 		t.Run(test.name, func(t *testing.T) {
 			var targetUser entity.User
@@ -281,3 +384,140 @@ func TestDelete(t *testing.T) {
 		tx.Rollback()
 	}
 }
+
+func TestFindDeletedByEmail(t *testing.T) {
+	tests := []struct {
+		name       string
+		inputEmail string
+		wantFound  bool
+	}{
+		{
+			name:       "success",
+			inputEmail: "deleted@test.com",
+			wantFound:  true,
+		},
+		{
+			name:       "not found when not deleted",
+			inputEmail: "test@test.com",
+			wantFound:  false,
+		},
+	}
+
+	for _, test := range tests {
+		// Create transaction
+		tx := testDB.Begin()
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+		helper.CreateTestUser(tx, "deleted", "deleted", "deleted@test.com", "password")
+		tx.Where("email = ?", "deleted@test.com").Delete(&entity.User{})
+
+		t.Run(test.name, func(t *testing.T) {
+			repo := &UserRepository{DB: tx}
+			user, err := repo.FindDeletedByEmail(test.inputEmail)
+			assert.NoError(t, err)
+			if test.wantFound {
+				assert.Equal(t, "deleted", user.Name)
+				assert.True(t, user.DeletedAt.Valid)
+			} else {
+				assert.Nil(t, user)
+			}
+		})
+		tx.Rollback()
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name         string
+		signUpHandle string
+		signUpEmail  string
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:         "success after sign-up with the deleted email was refused",
+			signUpHandle: "new",
+			signUpEmail:  "test@test.com",
+		},
+		{
+			name:         "success after sign-up with the deleted handle was refused",
+			signUpHandle: "test",
+			signUpEmail:  "new@test.com",
+		},
+	}
+
+	for _, test := range tests {
+		// Create transaction
+		tx := testDB.Begin()
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+		tx.Where("email = ?", "test@test.com").Delete(&entity.User{})
+
+		t.Run(test.name, func(t *testing.T) {
+			repo := &UserRepository{DB: tx}
+			if test.signUpEmail != "" {
+				// Deleted users keep their email and handle until purged
+				user, _ := entity.NewUser("new", test.signUpHandle, test.signUpEmail, "password")
+				_, err := repo.Create(user)
+				assert.ErrorIs(t, err, entity.ErrAlreadyExists)
+			}
+
+			var deletedUser entity.User
+			tx.Unscoped().Where("handle = ?", "test").First(&deletedUser)
+			assert.NoError(t, repo.Restore(&deletedUser))
+			user, _ := repo.FindByEmail("test@test.com")
+			assert.Equal(t, deletedUser.ID, user.ID)
+		})
+		tx.Rollback()
+	}
+}
+
+func TestPurgeDeletedBefore(t *testing.T) {
+	tests := []struct {
+		name       string
+		cutoff     time.Time
+		wantPurged int64
+	}{
+		{
+			name:       "success",
+			cutoff:     time.Now().Add(time.Hour),
+			wantPurged: 1,
+		},
+		{
+			name:       "nothing deleted before cutoff",
+			cutoff:     time.Now().Add(-time.Hour),
+			wantPurged: 0,
+		},
+	}
+
+	for _, test := range tests {
+		// Create transaction
+		tx := testDB.Begin()
+		helper.CreateTestUser(tx, "test", "test", "test@test.com", "password")
+		helper.CreateTestUser(tx, "deleted", "deleted", "deleted@test.com", "password")
+		var deletedUser entity.User
+		tx.Where("email = ?", "deleted@test.com").First(&deletedUser)
+		change, _, _ := entity.NewEmailChange(&deletedUser, "new@test.com", time.Now())
+		tx.Create(change)
+		var otherUser entity.User
+		tx.Where("email = ?", "test@test.com").First(&otherUser)
+		block, _ := entity.NewUserRelation(otherUser.ID, deletedUser.ID, entity.UserRelationBlock)
+		tx.Create(block)
+		tx.Delete(&deletedUser)
+
+		t.Run(test.name, func(t *testing.T) {
+			repo := &UserRepository{DB: tx}
+			purged, err := repo.PurgeDeletedBefore(test.cutoff)
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantPurged, purged)
+
+			var remainingUsers, remainingChanges, remainingRelations int64
+			tx.Unscoped().Model(&entity.User{}).Count(&remainingUsers)
+			tx.Unscoped().Model(&entity.EmailChange{}).Where("user_id = ?", deletedUser.ID).Count(&remainingChanges)
+			tx.Unscoped().Model(&entity.UserRelation{}).Where("target_id = ?", deletedUser.ID).Count(&remainingRelations)
+			assert.Equal(t, 2-test.wantPurged, remainingUsers)
+			assert.Equal(t, 1-test.wantPurged, remainingChanges)
+			assert.Equal(t, 1-test.wantPurged, remainingRelations)
+		})
+		tx.Rollback()
+	}
+}
@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPConfig is the configuration of an SMTP server
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer sends plain text emails through an SMTP server
+type SMTPMailer struct {
+	Config *SMTPConfig
+}
+
+// LogMailer writes emails to the log instead of sending them, for local development only.
+// The links in the emails carry working tokens, so the log must not be shared.
+type LogMailer struct{}
+
+func NewSMTPConfig(host, port, username, password, from string) (*SMTPConfig, error) {
+	if host == "" || port == "" || from == "" {
+		return nil, fmt.Errorf("smtp host, port, and from must not be empty")
+	}
+
+	return &SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+	}, nil
+}
+
+// NewSMTPMailer creates a new SMTP mailer
+func NewSMTPMailer(config *SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{Config: config}
+}
+
+// NewLogMailer creates a new log mailer
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send sends an email
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Config.Username != "" {
+		auth = smtp.PlainAuth("", m.Config.Username, m.Config.Password, m.Config.Host)
+	}
+
+	addr := net.JoinHostPort(m.Config.Host, m.Config.Port)
+	msg := newMessage(m.Config.From, to, subject, body)
+	if err := smtp.SendMail(addr, auth, m.Config.From, []string{to}, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// Send logs an email
+func (m *LogMailer) Send(to, subject, body string) error {
+	log.Printf("Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// newMessage builds a plain text message, rejecting header injection through the address or subject
+func newMessage(from, to, subject, body string) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	b.WriteString("From: " + clean.Replace(from) + "\r\n")
+	b.WriteString("To: " + clean.Replace(to) + "\r\n")
+	b.WriteString("Subject: " + clean.Replace(subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
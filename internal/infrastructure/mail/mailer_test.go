@@ -0,0 +1,90 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSMTPConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   map[string]string
+		wantErr bool
+	}{
+		{
+			name: "success",
+			input: map[string]string{
+				"host": "localhost",
+				"port": "1025",
+				"from": "noreply@test.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "error empty host",
+			input: map[string]string{
+				"host": "",
+				"port": "1025",
+				"from": "noreply@test.com",
+			},
+			wantErr: true,
+		},
+		{
+			name: "error empty from",
+			input: map[string]string{
+				"host": "localhost",
+				"port": "1025",
+				"from": "",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := NewSMTPConfig(test.input["host"], test.input["port"], "", "", test.input["from"])
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, config)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.input["host"], config.Host)
+			}
+		})
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		to      string
+		subject string
+		body    string
+		want    string
+	}{
+		{
+			name:    "success",
+			to:      "test@test.com",
+			subject: "Hello",
+			body:    "line1\nline2",
+			want: "From: noreply@test.com\r\nTo: test@test.com\r\nSubject: Hello\r\n" +
+				"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nline1\r\nline2",
+		},
+		{
+			name:    "header injection is removed",
+			to:      "test@test.com\r\nBcc: evil@test.com",
+			subject: "Hello\nBcc: evil@test.com",
+			body:    "body",
+			want: "From: noreply@test.com\r\nTo: test@test.comBcc: evil@test.com\r\nSubject: HelloBcc: evil@test.com\r\n" +
+				"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nbody",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg := newMessage("noreply@test.com", test.to, test.subject, test.body)
+			assert.Equal(t, test.want, string(msg))
+		})
+	}
+}
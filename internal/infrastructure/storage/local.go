@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LocalStore stores files in a directory of the local filesystem
+type LocalStore struct {
+	Dir string
+}
+
+// NewLocalStore creates a new local store, creating its directory if needed
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("storage directory cannot be empty")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &LocalStore{Dir: dir}, nil
+}
+
+// Save writes a file and returns the key to read it back
+func (s *LocalStore) Save(name string, data []byte) (string, error) {
+	key := filepath.Base(name)
+	if err := os.WriteFile(s.path(key), data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return key, nil
+}
+
+// Open opens a saved file
+func (s *LocalStore) Open(key string) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return file, nil
+}
+
+// Delete deletes a saved file, deleting a missing file is not an error
+func (s *LocalStore) Delete(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
+
+// path resolves a key inside the directory, keys cannot point outside of it
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.Dir, filepath.Base(key))
+}
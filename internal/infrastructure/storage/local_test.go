@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLocalStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			dir:     filepath.Join(t.TempDir(), "exports"),
+			wantErr: false,
+		},
+		{
+			name:    "error empty dir",
+			dir:     "",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := NewLocalStore(test.dir)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, store)
+			} else {
+				assert.NoError(t, err)
+				assert.DirExists(t, test.dir)
+			}
+		})
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantKey string
+	}{
+		{
+			name:    "success",
+			file:    "export.zip",
+			wantKey: "export.zip",
+		},
+		{
+			name:    "path traversal stays in the directory",
+			file:    "../../etc/export.zip",
+			wantKey: "export.zip",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := &LocalStore{Dir: dir}
+
+			key, err := store.Save(test.file, []byte("data"))
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantKey, key)
+			assert.FileExists(t, filepath.Join(dir, test.wantKey))
+
+			file, err := store.Open(key)
+			assert.NoError(t, err)
+			data, _ := io.ReadAll(file)
+			file.Close()
+			assert.Equal(t, "data", string(data))
+
+			assert.NoError(t, store.Delete(key))
+			_, err = os.Stat(filepath.Join(dir, test.wantKey))
+			assert.True(t, os.IsNotExist(err))
+			_, err = store.Open(key)
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+
+			// Deleting again is not an error
+			assert.NoError(t, store.Delete(key))
+		})
+	}
+}
@@ -21,6 +21,7 @@ type AuthHandler struct {
 
 type SignUpInput struct {
 	Name     string `json:"name"`
+	Handle   string `json:"handle"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
@@ -42,7 +43,7 @@ func (h *AuthHandler) SignUp(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, fmt.Errorf("invalid request"))
 	}
 
-	inputToUsecase := usecase.NewCreateUserInput(input.Name, input.Email, input.Password)
+	inputToUsecase := usecase.NewCreateUserInput(input.Name, input.Handle, input.Email, input.Password)
 	user, err := h.AuthUseCase.CreateUser(inputToUsecase)
 	if err != nil {
 		return err.ErrorResponse(c)
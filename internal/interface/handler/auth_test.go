@@ -53,7 +53,7 @@ func TestSignUp(t *testing.T) {
 	}{
 		{
 			name:           "success",
-			input:          `{"name": "test", "email": "test@test.com", "password": "password"}`,
+			input:          `{"name": "test", "handle": "test", "email": "test@test.com", "password": "password"}`,
 			expectedStatus: http.StatusOK,
 			mockReturn: []interface{}{
 				&usecase.UserResponse{
@@ -65,13 +65,13 @@ func TestSignUp(t *testing.T) {
 		},
 		{
 			name:           "invalid request for binding error",
-			input:          `{"name": "test", "email": "test@test.com", "password": }`,
+			input:          `{"name": "test", "handle": "test", "email": "test@test.com", "password": }`,
 			expectedStatus: http.StatusBadRequest,
 			mockReturn:     []interface{}{nil, nil},
 		},
 		{
 			name:           "invalid request",
-			input:          `{"name": "test", "email": "", "password": "password"}`,
+			input:          `{"name": "test", "handle": "test", "email": "", "password": "password"}`,
 			expectedStatus: http.StatusBadRequest,
 			mockReturn: []interface{}{
 				nil,
@@ -80,7 +80,7 @@ func TestSignUp(t *testing.T) {
 		},
 		{
 			name:           "internal server error",
-			input:          `{"name": "test", "email": "test@test.com", "password": "password"}`,
+			input:          `{"name": "test", "handle": "test", "email": "test@test.com", "password": "password"}`,
 			expectedStatus: http.StatusInternalServerError,
 			mockReturn: []interface{}{
 				nil,
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"chatapp/internal/usecase"
+	"chatapp/pkg/errors"
+
+	"github.com/labstack/echo/v4"
+)
+
+const MIMEApplicationZip = "application/zip"
+
+type DataExportUseCase interface {
+	RequestExport(input *usecase.RequestExportInput) (*usecase.DataExportResponse, *errors.CustomError)
+	ReadExport(userID, exportID string) (*usecase.DataExportResponse, *errors.CustomError)
+	OpenExportDownload(exportID, token string) (io.ReadCloser, string, *errors.CustomError)
+}
+
+type DataExportHandler struct {
+	DataExportUseCase DataExportUseCase
+}
+
+type RequestExportRequest struct {
+	Password string `json:"password"`
+}
+
+// DownloadExportRequest carries the download token in the body, never in the URL,
+// so that it does not end up in access logs. A plain HTML form can post it too.
+type DownloadExportRequest struct {
+	Token string `json:"token" form:"token"`
+}
+
+func NewDataExportHandler(dataExportUseCase DataExportUseCase) *DataExportHandler {
+	return &DataExportHandler{
+		DataExportUseCase: dataExportUseCase,
+	}
+}
+
+func (h *DataExportHandler) RequestExport(c echo.Context) error {
+	var req RequestExportRequest
+	if err := c.Bind(&req); err != nil {
+		customError := errors.NewCustomError(errors.BadRequest, err)
+		return customError.ErrorResponse(c)
+	}
+
+	userID := c.Param("id")
+	inputToUseCase := usecase.NewRequestExportInput(userID, req.Password)
+	export, customErr := h.DataExportUseCase.RequestExport(inputToUseCase)
+	if customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusAccepted, export)
+}
+
+func (h *DataExportHandler) RetrieveExport(c echo.Context) error {
+	export, customErr := h.DataExportUseCase.ReadExport(c.Param("id"), c.Param("exportId"))
+	if customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusOK, export)
+}
+
+func (h *DataExportHandler) DownloadExport(c echo.Context) error {
+	var req DownloadExportRequest
+	if err := c.Bind(&req); err != nil {
+		customError := errors.NewCustomError(errors.BadRequest, err)
+		return customError.ErrorResponse(c)
+	}
+
+	file, name, customErr := h.DataExportUseCase.OpenExportDownload(c.Param("id"), req.Token)
+	if customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+	defer file.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+name+`"`)
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Stream(http.StatusOK, MIMEApplicationZip, file)
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chatapp/internal/usecase"
+	"chatapp/pkg/errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDataExportUseCase struct {
+	mock.Mock
+}
+
+func (m *mockDataExportUseCase) RequestExport(input *usecase.RequestExportInput) (*usecase.DataExportResponse, *errors.CustomError) {
+	args := m.Called(input)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*errors.CustomError)
+	}
+	return args.Get(0).(*usecase.DataExportResponse), nil
+}
+
+func (m *mockDataExportUseCase) ReadExport(userID, exportID string) (*usecase.DataExportResponse, *errors.CustomError) {
+	args := m.Called(userID, exportID)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*errors.CustomError)
+	}
+	return args.Get(0).(*usecase.DataExportResponse), nil
+}
+
+func (m *mockDataExportUseCase) OpenExportDownload(exportID, token string) (io.ReadCloser, string, *errors.CustomError) {
+	args := m.Called(exportID, token)
+	if args.Get(0) == nil {
+		return nil, "", args.Get(2).(*errors.CustomError)
+	}
+	return args.Get(0).(io.ReadCloser), args.String(1), nil
+}
+
+func TestRequestExport(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		mockReturn []interface{}
+		wantStatus int
+	}{
+		{
+			name:  "success",
+			input: `{"password": "password"}`,
+			mockReturn: []interface{}{
+				&usecase.DataExportResponse{ID: uint(1), Status: "pending", DownloadToken: "token"},
+				nil,
+			},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "invalid request for binding error",
+			input:      `{"password": }`,
+			mockReturn: []interface{}{nil, nil},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid credentials",
+			input: `{"password": "wrong"}`,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.InvalidCredentials, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockDataExportUseCase mockDataExportUseCase
+			mockDataExportUseCase.On("RequestExport", mock.Anything).Return(test.mockReturn...)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/users/:id/export", strings.NewReader(test.input))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			dataExportHandler := NewDataExportHandler(&mockDataExportUseCase)
+			dataExportHandler.RequestExport(c)
+			assert.Equal(t, test.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestRetrieveExport(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockReturn []interface{}
+		wantStatus int
+	}{
+		{
+			name: "success",
+			mockReturn: []interface{}{
+				&usecase.DataExportResponse{ID: uint(1), Status: "ready"},
+				nil,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "export not found",
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.NotFound, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockDataExportUseCase mockDataExportUseCase
+			mockDataExportUseCase.On("ReadExport", "1", "2").Return(test.mockReturn...)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/users/:id/export/:exportId", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "exportId")
+			c.SetParamValues("1", "2")
+
+			dataExportHandler := NewDataExportHandler(&mockDataExportUseCase)
+			dataExportHandler.RetrieveExport(c)
+			assert.Equal(t, test.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestDownloadExport(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		contentType string
+		mockReturn  []interface{}
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "success",
+			input:       `{"token": "token"}`,
+			contentType: echo.MIMEApplicationJSON,
+			mockReturn: []interface{}{
+				io.NopCloser(strings.NewReader("zip")),
+				"chatapp-export-1.zip",
+				nil,
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "zip",
+		},
+		{
+			name:        "success with a form",
+			input:       "token=token",
+			contentType: echo.MIMEApplicationForm,
+			mockReturn: []interface{}{
+				io.NopCloser(strings.NewReader("zip")),
+				"chatapp-export-1.zip",
+				nil,
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "zip",
+		},
+		{
+			name:        "invalid request for binding error",
+			input:       `{"token": }`,
+			contentType: echo.MIMEApplicationJSON,
+			mockReturn:  []interface{}{nil, "", nil},
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "export not found",
+			input:       `{"token": "token"}`,
+			contentType: echo.MIMEApplicationJSON,
+			mockReturn: []interface{}{
+				nil,
+				"",
+				errors.NewCustomError(errors.NotFound, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockDataExportUseCase mockDataExportUseCase
+			mockDataExportUseCase.On("OpenExportDownload", "1", "token").Return(test.mockReturn...)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/exports/:id/download", strings.NewReader(test.input))
+			req.Header.Set(echo.HeaderContentType, test.contentType)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			dataExportHandler := NewDataExportHandler(&mockDataExportUseCase)
+			dataExportHandler.DownloadExport(c)
+			assert.Equal(t, test.wantStatus, rec.Code)
+			if test.wantStatus == http.StatusOK {
+				assert.Equal(t, test.wantBody, rec.Body.String())
+				assert.Equal(t, MIMEApplicationZip, rec.Header().Get(echo.HeaderContentType))
+				assert.Equal(t, `attachment; filename="chatapp-export-1.zip"`, rec.Header().Get(echo.HeaderContentDisposition))
+			}
+		})
+	}
+}
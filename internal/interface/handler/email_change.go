@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+
+	"chatapp/internal/usecase"
+	"chatapp/pkg/errors"
+
+	"github.com/labstack/echo/v4"
+)
+
+type EmailChangeUseCase interface {
+	RequestEmailChange(input *usecase.RequestEmailChangeInput) *errors.CustomError
+	ConfirmEmailChange(token string) (*usecase.UserResponse, *errors.CustomError)
+	RevertEmailChange(token string) (*usecase.UserResponse, *errors.CustomError)
+}
+
+type EmailChangeHandler struct {
+	EmailChangeUseCase EmailChangeUseCase
+}
+
+type RequestEmailChangeRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type EmailChangeTokenRequest struct {
+	Token string `json:"token"`
+}
+
+func NewEmailChangeHandler(emailChangeUseCase EmailChangeUseCase) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		EmailChangeUseCase: emailChangeUseCase,
+	}
+}
+
+func (h *EmailChangeHandler) RequestEmailChange(c echo.Context) error {
+	var req RequestEmailChangeRequest
+	if err := c.Bind(&req); err != nil {
+		customError := errors.NewCustomError(errors.BadRequest, err)
+		return customError.ErrorResponse(c)
+	}
+
+	userID := c.Param("id")
+	inputToUseCase := usecase.NewRequestEmailChangeInput(userID, req.Email, req.Password)
+	if customErr := h.EmailChangeUseCase.RequestEmailChange(inputToUseCase); customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusAccepted, nil)
+}
+
+func (h *EmailChangeHandler) ConfirmEmailChange(c echo.Context) error {
+	var req EmailChangeTokenRequest
+	if err := c.Bind(&req); err != nil {
+		customError := errors.NewCustomError(errors.BadRequest, err)
+		return customError.ErrorResponse(c)
+	}
+
+	user, customErr := h.EmailChangeUseCase.ConfirmEmailChange(req.Token)
+	if customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+func (h *EmailChangeHandler) RevertEmailChange(c echo.Context) error {
+	var req EmailChangeTokenRequest
+	if err := c.Bind(&req); err != nil {
+		customError := errors.NewCustomError(errors.BadRequest, err)
+		return customError.ErrorResponse(c)
+	}
+
+	user, customErr := h.EmailChangeUseCase.RevertEmailChange(req.Token)
+	if customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
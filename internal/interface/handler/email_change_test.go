@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chatapp/internal/usecase"
+	"chatapp/pkg/errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockEmailChangeUseCase struct {
+	mock.Mock
+}
+
+func (m *mockEmailChangeUseCase) RequestEmailChange(input *usecase.RequestEmailChangeInput) *errors.CustomError {
+	args := m.Called(input)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*errors.CustomError)
+}
+
+func (m *mockEmailChangeUseCase) ConfirmEmailChange(token string) (*usecase.UserResponse, *errors.CustomError) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*errors.CustomError)
+	}
+	return args.Get(0).(*usecase.UserResponse), nil
+}
+
+func (m *mockEmailChangeUseCase) RevertEmailChange(token string) (*usecase.UserResponse, *errors.CustomError) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*errors.CustomError)
+	}
+	return args.Get(0).(*usecase.UserResponse), nil
+}
+
+func TestRequestEmailChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		mockReturn []interface{}
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			input:      `{"email": "new@test.com", "password": "password"}`,
+			mockReturn: []interface{}{nil},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "invalid request for binding error",
+			input:      `{"email": }`,
+			mockReturn: []interface{}{nil},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid credentials",
+			input: `{"email": "new@test.com", "password": "wrong"}`,
+			mockReturn: []interface{}{
+				errors.NewCustomError(errors.InvalidCredentials, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "email already taken",
+			input: `{"email": "taken@test.com", "password": "password"}`,
+			mockReturn: []interface{}{
+				errors.NewCustomError(errors.Conflict, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockEmailChangeUseCase mockEmailChangeUseCase
+			mockEmailChangeUseCase.On("RequestEmailChange", mock.Anything).Return(test.mockReturn...)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/users/:id/email", strings.NewReader(test.input))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			emailChangeHandler := NewEmailChangeHandler(&mockEmailChangeUseCase)
+			emailChangeHandler.RequestEmailChange(c)
+			assert.Equal(t, test.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		mockReturn []interface{}
+		wantStatus int
+	}{
+		{
+			name:  "success",
+			input: `{"token": "token"}`,
+			mockReturn: []interface{}{
+				&usecase.UserResponse{ID: uint(1), Name: "test"},
+				nil,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "unknown token",
+			input: `{"token": "unknown"}`,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.NotFound, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:  "email already taken",
+			input: `{"token": "token"}`,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.Conflict, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockEmailChangeUseCase mockEmailChangeUseCase
+			mockEmailChangeUseCase.On("ConfirmEmailChange", mock.Anything).Return(test.mockReturn...)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/users/email/confirm", strings.NewReader(test.input))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			emailChangeHandler := NewEmailChangeHandler(&mockEmailChangeUseCase)
+			emailChangeHandler.ConfirmEmailChange(c)
+			assert.Equal(t, test.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestRevertEmailChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		mockReturn []interface{}
+		wantStatus int
+	}{
+		{
+			name:  "success",
+			input: `{"token": "token"}`,
+			mockReturn: []interface{}{
+				&usecase.UserResponse{ID: uint(1), Name: "test"},
+				nil,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "expired token",
+			input: `{"token": "token"}`,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.BadRequest, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockEmailChangeUseCase mockEmailChangeUseCase
+			mockEmailChangeUseCase.On("RevertEmailChange", mock.Anything).Return(test.mockReturn...)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/users/email/revert", strings.NewReader(test.input))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			emailChangeHandler := NewEmailChangeHandler(&mockEmailChangeUseCase)
+			emailChangeHandler.RevertEmailChange(c)
+			assert.Equal(t, test.wantStatus, rec.Code)
+		})
+	}
+}
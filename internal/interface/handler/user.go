@@ -1,19 +1,30 @@
 package handler
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"chatapp/internal/usecase"
 	"chatapp/pkg/errors"
+	"chatapp/pkg/patch"
 
 	"github.com/labstack/echo/v4"
 )
 
+// MIMEApplicationMergePatchJSON is the media type of a JSON Merge Patch document
+const MIMEApplicationMergePatchJSON = "application/merge-patch+json"
+
 type UserUseCase interface {
 	ReadUser(userID string) (*usecase.UserResponse, *errors.CustomError)
-	ReadAllUsers() (*usecase.UsersResponse, *errors.CustomError)
-	UpdateUser(input *usecase.UpdateUserInput) *errors.CustomError
+	ReadUsers(input *usecase.ListUsersInput) (*usecase.UsersResponse, *errors.CustomError)
+	UpdateUser(input *usecase.UpdateUserInput) (*usecase.UserResponse, *errors.CustomError)
+	PatchUser(input *usecase.PatchUserInput) (*usecase.UserResponse, *errors.CustomError)
+	UpdateProfile(input *usecase.UpdateProfileInput) (*usecase.UserResponse, *errors.CustomError)
 	DestroyUser(userID string) *errors.CustomError
+	RestoreUser(input *usecase.AuthenticateUserInput) (*usecase.UserResponse, *errors.CustomError)
 }
 
 type UserHandler struct {
@@ -25,6 +36,26 @@ type UpdateUserRequest struct {
 	Email string `json:"email"`
 }
 
+type RestoreUserRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// PatchUserRequest is a JSON Merge Patch document for a user
+type PatchUserRequest struct {
+	Name  patch.Field[string] `json:"name"`
+	Email patch.Field[string] `json:"email"`
+}
+
+// UpdateProfileRequest holds the profile fields to change, omitted fields are left as they are
+type UpdateProfileRequest struct {
+	Handle      *string `json:"handle"`
+	DisplayName *string `json:"display_name"`
+	Bio         *string `json:"bio"`
+	Timezone    *string `json:"timezone"`
+	Locale      *string `json:"locale"`
+}
+
 func NewUserHandler(userService UserUseCase) *UserHandler {
 	return &UserHandler{
 		UserUseCase: userService,
@@ -42,7 +73,24 @@ func (h *UserHandler) RetrieveUser(c echo.Context) error {
 }
 
 func (h *UserHandler) ListUsers(c echo.Context) error {
-	users, customErr := h.UserUseCase.ReadAllUsers()
+	var limit int
+	if rawLimit := c.QueryParam("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			customErr := errors.NewCustomError(errors.BadRequest, err)
+			return customErr.ErrorResponse(c)
+		}
+		limit = parsed
+	}
+
+	inputToUseCase := usecase.NewListUsersInput(
+		c.QueryParam("q"),
+		c.QueryParam("cursor"),
+		limit,
+		c.QueryParam("sort"),
+		c.QueryParam("order"),
+	)
+	users, customErr := h.UserUseCase.ReadUsers(inputToUseCase)
 	if customErr != nil {
 		return customErr.ErrorResponse(c)
 	}
@@ -53,17 +101,61 @@ func (h *UserHandler) ListUsers(c echo.Context) error {
 func (h *UserHandler) UpdateUserInfo(c echo.Context) error {
 	var req UpdateUserRequest
 	if err := c.Bind(&req); err != nil {
-		customError := errors.NewCustomError(http.StatusBadRequest, err)
+		customError := errors.NewCustomError(errors.BadRequest, err)
 		return customError.ErrorResponse(c)
 	}
 
 	userID := c.Param("id")
 	inputToUseCase := usecase.NewUpdateUserInput(userID, req.Name, req.Email)
-	if customErr := h.UserUseCase.UpdateUser(inputToUseCase); customErr != nil {
+	user, customErr := h.UserUseCase.UpdateUser(inputToUseCase)
+	if customErr != nil {
 		return customErr.ErrorResponse(c)
 	}
 
-	return c.JSON(http.StatusNoContent, nil)
+	return c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) PatchUser(c echo.Context) error {
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	if !strings.HasPrefix(contentType, echo.MIMEApplicationJSON) && !strings.HasPrefix(contentType, MIMEApplicationMergePatchJSON) {
+		customError := errors.NewCustomError(errors.BadRequest, fmt.Errorf("unsupported content type: %s", contentType))
+		return customError.ErrorResponse(c)
+	}
+
+	// Unknown members are rejected so that read-only fields cannot be patched silently
+	var req PatchUserRequest
+	decoder := json.NewDecoder(c.Request().Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		customError := errors.NewCustomError(errors.BadRequest, err)
+		return customError.ErrorResponse(c)
+	}
+
+	userID := c.Param("id")
+	inputToUseCase := usecase.NewPatchUserInput(userID, req.Name, req.Email)
+	user, customErr := h.UserUseCase.PatchUser(inputToUseCase)
+	if customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) UpdateProfile(c echo.Context) error {
+	var req UpdateProfileRequest
+	if err := c.Bind(&req); err != nil {
+		customError := errors.NewCustomError(errors.BadRequest, err)
+		return customError.ErrorResponse(c)
+	}
+
+	userID := c.Param("id")
+	inputToUseCase := usecase.NewUpdateProfileInput(userID, req.Handle, req.DisplayName, req.Bio, req.Timezone, req.Locale)
+	user, customErr := h.UserUseCase.UpdateProfile(inputToUseCase)
+	if customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusOK, user)
 }
 
 func (h *UserHandler) DeleteUser(c echo.Context) error {
@@ -75,3 +167,19 @@ func (h *UserHandler) DeleteUser(c echo.Context) error {
 
 	return c.JSON(http.StatusNoContent, nil)
 }
+
+func (h *UserHandler) RestoreUser(c echo.Context) error {
+	var req RestoreUserRequest
+	if err := c.Bind(&req); err != nil {
+		customError := errors.NewCustomError(errors.BadRequest, err)
+		return customError.ErrorResponse(c)
+	}
+
+	inputToUseCase := usecase.NewAuthenticateUserInput(req.Email, req.Password)
+	user, customErr := h.UserUseCase.RestoreUser(inputToUseCase)
+	if customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+
+	"chatapp/internal/usecase"
+	"chatapp/pkg/errors"
+
+	"github.com/labstack/echo/v4"
+)
+
+type UserRelationUseCase interface {
+	BlockUser(userID, targetID string) *errors.CustomError
+	UnblockUser(userID, targetID string) *errors.CustomError
+	ReadBlockedUsers(userID string) ([]usecase.UserResponse, *errors.CustomError)
+	MuteUser(userID, targetID string) *errors.CustomError
+	UnmuteUser(userID, targetID string) *errors.CustomError
+	ReadMutedUsers(userID string) ([]usecase.UserResponse, *errors.CustomError)
+}
+
+type UserRelationHandler struct {
+	UserRelationUseCase UserRelationUseCase
+}
+
+func NewUserRelationHandler(userRelationUseCase UserRelationUseCase) *UserRelationHandler {
+	return &UserRelationHandler{
+		UserRelationUseCase: userRelationUseCase,
+	}
+}
+
+func (h *UserRelationHandler) ListBlockedUsers(c echo.Context) error {
+	users, customErr := h.UserRelationUseCase.ReadBlockedUsers(c.Param("id"))
+	if customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusOK, users)
+}
+
+func (h *UserRelationHandler) BlockUser(c echo.Context) error {
+	if customErr := h.UserRelationUseCase.BlockUser(c.Param("id"), c.Param("targetId")); customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *UserRelationHandler) UnblockUser(c echo.Context) error {
+	if customErr := h.UserRelationUseCase.UnblockUser(c.Param("id"), c.Param("targetId")); customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *UserRelationHandler) ListMutedUsers(c echo.Context) error {
+	users, customErr := h.UserRelationUseCase.ReadMutedUsers(c.Param("id"))
+	if customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusOK, users)
+}
+
+func (h *UserRelationHandler) MuteUser(c echo.Context) error {
+	if customErr := h.UserRelationUseCase.MuteUser(c.Param("id"), c.Param("targetId")); customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *UserRelationHandler) UnmuteUser(c echo.Context) error {
+	if customErr := h.UserRelationUseCase.UnmuteUser(c.Param("id"), c.Param("targetId")); customErr != nil {
+		return customErr.ErrorResponse(c)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
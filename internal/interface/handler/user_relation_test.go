@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chatapp/internal/usecase"
+	"chatapp/pkg/errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockUserRelationUseCase struct {
+	mock.Mock
+}
+
+func (m *mockUserRelationUseCase) BlockUser(userID, targetID string) *errors.CustomError {
+	return m.customError(m.Called(userID, targetID))
+}
+
+func (m *mockUserRelationUseCase) UnblockUser(userID, targetID string) *errors.CustomError {
+	return m.customError(m.Called(userID, targetID))
+}
+
+func (m *mockUserRelationUseCase) ReadBlockedUsers(userID string) ([]usecase.UserResponse, *errors.CustomError) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*errors.CustomError)
+	}
+	return args.Get(0).([]usecase.UserResponse), nil
+}
+
+func (m *mockUserRelationUseCase) MuteUser(userID, targetID string) *errors.CustomError {
+	return m.customError(m.Called(userID, targetID))
+}
+
+func (m *mockUserRelationUseCase) UnmuteUser(userID, targetID string) *errors.CustomError {
+	return m.customError(m.Called(userID, targetID))
+}
+
+func (m *mockUserRelationUseCase) ReadMutedUsers(userID string) ([]usecase.UserResponse, *errors.CustomError) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*errors.CustomError)
+	}
+	return args.Get(0).([]usecase.UserResponse), nil
+}
+
+func (m *mockUserRelationUseCase) customError(args mock.Arguments) *errors.CustomError {
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*errors.CustomError)
+}
+
+func TestListUserRelations(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		mockReturn []interface{}
+		wantStatus int
+	}{
+		{
+			name:   "success blocked users",
+			method: "ReadBlockedUsers",
+			mockReturn: []interface{}{
+				[]usecase.UserResponse{{ID: uint(2), Name: "target"}},
+				nil,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "success muted users",
+			method: "ReadMutedUsers",
+			mockReturn: []interface{}{
+				[]usecase.UserResponse{},
+				nil,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "user not found",
+			method: "ReadBlockedUsers",
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.NotFound, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserRelationUseCase mockUserRelationUseCase
+			mockUserRelationUseCase.On(test.method, "1").Return(test.mockReturn...)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/users/:id/blocks", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			userRelationHandler := NewUserRelationHandler(&mockUserRelationUseCase)
+			if test.method == "ReadBlockedUsers" {
+				userRelationHandler.ListBlockedUsers(c)
+			} else {
+				userRelationHandler.ListMutedUsers(c)
+			}
+			assert.Equal(t, test.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestChangeUserRelation(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		mockReturn []interface{}
+		wantStatus int
+	}{
+		{
+			name:       "success block",
+			method:     "BlockUser",
+			mockReturn: []interface{}{nil},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "success unblock",
+			method:     "UnblockUser",
+			mockReturn: []interface{}{nil},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "success mute",
+			method:     "MuteUser",
+			mockReturn: []interface{}{nil},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "success unmute",
+			method:     "UnmuteUser",
+			mockReturn: []interface{}{nil},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:   "blocking yourself",
+			method: "BlockUser",
+			mockReturn: []interface{}{
+				errors.NewCustomError(errors.BadRequest, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "target not found",
+			method: "MuteUser",
+			mockReturn: []interface{}{
+				errors.NewCustomError(errors.NotFound, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserRelationUseCase mockUserRelationUseCase
+			mockUserRelationUseCase.On(test.method, "1", "2").Return(test.mockReturn...)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/users/:id/blocks/:targetId", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "targetId")
+			c.SetParamValues("1", "2")
+
+			userRelationHandler := NewUserRelationHandler(&mockUserRelationUseCase)
+			handlers := map[string]echo.HandlerFunc{
+				"BlockUser":   userRelationHandler.BlockUser,
+				"UnblockUser": userRelationHandler.UnblockUser,
+				"MuteUser":    userRelationHandler.MuteUser,
+				"UnmuteUser":  userRelationHandler.UnmuteUser,
+			}
+			handlers[test.method](c)
+			assert.Equal(t, test.wantStatus, rec.Code)
+			mockUserRelationUseCase.AssertExpectations(t)
+		})
+	}
+}
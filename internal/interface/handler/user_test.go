@@ -9,6 +9,7 @@ import (
 
 	"chatapp/internal/usecase"
 	"chatapp/pkg/errors"
+	"chatapp/pkg/patch"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -27,8 +28,8 @@ func (m *mockUserUseCase) ReadUser(userID string) (*usecase.UserResponse, *error
 	return args.Get(0).(*usecase.UserResponse), nil
 }
 
-func (m *mockUserUseCase) ReadAllUsers() (*usecase.UsersResponse, *errors.CustomError) {
-	args := m.Called()
+func (m *mockUserUseCase) ReadUsers(input *usecase.ListUsersInput) (*usecase.UsersResponse, *errors.CustomError) {
+	args := m.Called(input)
 	if args.Get(0) == nil && args.Get(1) == nil {
 		return nil, nil
 	}
@@ -40,12 +41,28 @@ func (m *mockUserUseCase) ReadAllUsers() (*usecase.UsersResponse, *errors.Custom
 	return args.Get(0).(*usecase.UsersResponse), nil
 }
 
-func (m *mockUserUseCase) UpdateUser(input *usecase.UpdateUserInput) *errors.CustomError {
+func (m *mockUserUseCase) UpdateUser(input *usecase.UpdateUserInput) (*usecase.UserResponse, *errors.CustomError) {
 	args := m.Called(input)
 	if args.Get(0) == nil {
-		return nil
+		return nil, args.Get(1).(*errors.CustomError)
 	}
-	return args.Get(0).(*errors.CustomError)
+	return args.Get(0).(*usecase.UserResponse), nil
+}
+
+func (m *mockUserUseCase) PatchUser(input *usecase.PatchUserInput) (*usecase.UserResponse, *errors.CustomError) {
+	args := m.Called(input)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*errors.CustomError)
+	}
+	return args.Get(0).(*usecase.UserResponse), nil
+}
+
+func (m *mockUserUseCase) UpdateProfile(input *usecase.UpdateProfileInput) (*usecase.UserResponse, *errors.CustomError) {
+	args := m.Called(input)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*errors.CustomError)
+	}
+	return args.Get(0).(*usecase.UserResponse), nil
 }
 
 func (m *mockUserUseCase) DestroyUser(userID string) *errors.CustomError {
@@ -56,6 +73,14 @@ func (m *mockUserUseCase) DestroyUser(userID string) *errors.CustomError {
 	return args.Get(0).(*errors.CustomError)
 }
 
+func (m *mockUserUseCase) RestoreUser(input *usecase.AuthenticateUserInput) (*usecase.UserResponse, *errors.CustomError) {
+	args := m.Called(input)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*errors.CustomError)
+	}
+	return args.Get(0).(*usecase.UserResponse), nil
+}
+
 func TestRetrieveUser(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -109,11 +134,13 @@ func TestRetrieveUser(t *testing.T) {
 func TestListUsers(t *testing.T) {
 	tests := []struct {
 		name       string
+		query      string
 		mockReturn []interface{}
 		wantStatus int
 	}{
 		{
-			name: "success",
+			name:  "success",
+			query: "",
 			mockReturn: []interface{}{
 				&usecase.UsersResponse{
 					Users: []usecase.UserResponse{
@@ -132,7 +159,40 @@ func TestListUsers(t *testing.T) {
 			wantStatus: http.StatusOK,
 		},
 		{
-			name: "error when reading users",
+			name:  "success with search and pagination",
+			query: "?q=te&limit=1&sort=name&order=desc&cursor=abc",
+			mockReturn: []interface{}{
+				&usecase.UsersResponse{
+					Users: []usecase.UserResponse{
+						{
+							ID:   uint(2),
+							Name: "test2",
+						},
+					},
+					NextCursor: "next",
+				},
+				nil,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid limit",
+			query:      "?limit=abc",
+			mockReturn: []interface{}{nil, nil},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid request",
+			query: "?sort=password",
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.BadRequest, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "error when reading users",
+			query: "",
 			mockReturn: []interface{}{
 				nil,
 				errors.NewCustomError(errors.InternalServerError, fmt.Errorf("error")),
@@ -144,11 +204,11 @@ func TestListUsers(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			var mockUserUseCase mockUserUseCase
-			mockUserUseCase.On("ReadAllUsers").Return(test.mockReturn...)
+			mockUserUseCase.On("ReadUsers", mock.Anything).Return(test.mockReturn...)
 
 			userHandler := NewUserHandler(&mockUserUseCase)
 			e := echo.New()
-			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req := httptest.NewRequest(http.MethodGet, "/users"+test.query, nil)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
 
@@ -166,15 +226,37 @@ func TestUpdateUserInfo(t *testing.T) {
 		wantStatus int
 	}{
 		{
-			name:       "success",
-			input:      `{ "name": "test", "email": "test@test.com"}`,
-			mockReturn: []interface{}{nil},
-			wantStatus: http.StatusNoContent,
+			name:  "success",
+			input: `{ "name": "test", "email": "test@test.com"}`,
+			mockReturn: []interface{}{
+				&usecase.UserResponse{
+					ID:   uint(1),
+					Name: "test",
+				},
+				nil,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid request for binding error",
+			input:      `{ "name": }`,
+			mockReturn: []interface{}{nil, nil},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid fields",
+			input: `{ "name": "test"}`,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewValidationError(map[string]string{"email": "email must not be empty"}),
+			},
+			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:  "error when updating user",
 			input: `{ "name": "test", "email": "test@test.com"}`,
 			mockReturn: []interface{}{
+				nil,
 				errors.NewCustomError(errors.InternalServerError, fmt.Errorf("error")),
 			},
 			wantStatus: http.StatusInternalServerError,
@@ -201,6 +283,170 @@ func TestUpdateUserInfo(t *testing.T) {
 	}
 }
 
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		contentType string
+		mockReturn  []interface{}
+		wantInput   *usecase.PatchUserInput
+		wantStatus  int
+	}{
+		{
+			name:        "success",
+			input:       `{"name": "updated"}`,
+			contentType: MIMEApplicationMergePatchJSON,
+			mockReturn: []interface{}{
+				&usecase.UserResponse{
+					ID:   uint(1),
+					Name: "updated",
+				},
+				nil,
+			},
+			wantInput: &usecase.PatchUserInput{
+				UserID: "1",
+				Name:   patch.NewField("updated"),
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "success with json content type and null member",
+			input:       `{"email": null}`,
+			contentType: echo.MIMEApplicationJSON,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewValidationError(map[string]string{"email": "email must not be null"}),
+			},
+			wantInput: &usecase.PatchUserInput{
+				UserID: "1",
+				Email:  patch.Field[string]{Set: true, Null: true},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "unknown field",
+			input:       `{"password": "password"}`,
+			contentType: MIMEApplicationMergePatchJSON,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "not an object",
+			input:       `["name"]`,
+			contentType: MIMEApplicationMergePatchJSON,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "unsupported content type",
+			input:       `name=updated`,
+			contentType: echo.MIMEApplicationForm,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "user not found",
+			input:       `{"name": "updated"}`,
+			contentType: MIMEApplicationMergePatchJSON,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.NotFound, fmt.Errorf("error")),
+			},
+			wantInput: &usecase.PatchUserInput{
+				UserID: "1",
+				Name:   patch.NewField("updated"),
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserUseCase mockUserUseCase
+			if test.wantInput != nil {
+				mockUserUseCase.On("PatchUser", test.wantInput).Return(test.mockReturn...)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPatch, "/users/:id", strings.NewReader(test.input))
+			req.Header.Set(echo.HeaderContentType, test.contentType)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			userHandler := NewUserHandler(&mockUserUseCase)
+			userHandler.PatchUser(c)
+			assert.Equal(t, test.wantStatus, rec.Code)
+			mockUserUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		mockReturn []interface{}
+		wantStatus int
+	}{
+		{
+			name:  "success",
+			input: `{"display_name": "Test User", "timezone": "Asia/Tokyo"}`,
+			mockReturn: []interface{}{
+				&usecase.UserResponse{
+					ID:          uint(1),
+					Name:        "test",
+					DisplayName: "Test User",
+					Timezone:    "Asia/Tokyo",
+				},
+				nil,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid request for binding error",
+			input:      `{"display_name": }`,
+			mockReturn: []interface{}{nil, nil},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid profile",
+			input: `{"locale": "-"}`,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.BadRequest, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "handle already taken",
+			input: `{"handle": "taken"}`,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.Conflict, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserUseCase mockUserUseCase
+			mockUserUseCase.On("UpdateProfile", mock.Anything).Return(test.mockReturn...)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPatch, "/users/:id/profile", strings.NewReader(test.input))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			userHandler := NewUserHandler(&mockUserUseCase)
+			userHandler.UpdateProfile(c)
+			assert.Equal(t, test.wantStatus, rec.Code)
+		})
+	}
+}
+
 func TestDeleteUser(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -239,3 +485,63 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestRestoreUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		mockReturn []interface{}
+		wantStatus int
+	}{
+		{
+			name:  "success",
+			input: `{"email": "test@test.com", "password": "password"}`,
+			mockReturn: []interface{}{
+				&usecase.UserResponse{ID: uint(1), Name: "test"},
+				nil,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid request for binding error",
+			input:      `{"email": }`,
+			mockReturn: []interface{}{nil, nil},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "not found or past retention",
+			input: `{"email": "test@test.com", "password": "password"}`,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.NotFound, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:  "email registered again",
+			input: `{"email": "test@test.com", "password": "password"}`,
+			mockReturn: []interface{}{
+				nil,
+				errors.NewCustomError(errors.Conflict, fmt.Errorf("error")),
+			},
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserUseCase mockUserUseCase
+			mockUserUseCase.On("RestoreUser", mock.Anything).Return(test.mockReturn...)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/users/restore", strings.NewReader(test.input))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			userHandler := NewUserHandler(&mockUserUseCase)
+			userHandler.RestoreUser(c)
+			assert.Equal(t, test.wantStatus, rec.Code)
+		})
+	}
+}
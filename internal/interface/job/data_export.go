@@ -0,0 +1,50 @@
+package job
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chatapp/pkg/errors"
+)
+
+type DataExportUseCase interface {
+	ProcessPendingExports() (int, *errors.CustomError)
+	DeleteExpiredExports() (int, *errors.CustomError)
+}
+
+// DataExportJob periodically builds the pending data exports and deletes the expired ones.
+// Every export is claimed by a single worker, so it can run on several instances as long as
+// they share the export directory. Otherwise downloads fail on the instances without the archive.
+type DataExportJob struct {
+	DataExportUseCase DataExportUseCase
+	Interval          time.Duration
+}
+
+func NewDataExportJob(dataExportUseCase DataExportUseCase, interval time.Duration) *DataExportJob {
+	return &DataExportJob{
+		DataExportUseCase: dataExportUseCase,
+		Interval:          interval,
+	}
+}
+
+// Run processes the exports once right away and then at every interval until the context is done
+func (j *DataExportJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, customErr := j.DataExportUseCase.ProcessPendingExports(); customErr != nil {
+			log.Println("failed to process data exports:", customErr.Error)
+		}
+		if _, customErr := j.DataExportUseCase.DeleteExpiredExports(); customErr != nil {
+			log.Println("failed to delete expired data exports:", customErr.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"chatapp/pkg/errors"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDataExportUseCase struct {
+	mock.Mock
+}
+
+func (m *mockDataExportUseCase) ProcessPendingExports() (int, *errors.CustomError) {
+	args := m.Called()
+	if args.Get(1) == nil {
+		return args.Int(0), nil
+	}
+	return args.Int(0), args.Get(1).(*errors.CustomError)
+}
+
+func (m *mockDataExportUseCase) DeleteExpiredExports() (int, *errors.CustomError) {
+	args := m.Called()
+	if args.Get(1) == nil {
+		return args.Int(0), nil
+	}
+	return args.Int(0), args.Get(1).(*errors.CustomError)
+}
+
+func TestDataExportJobRun(t *testing.T) {
+	tests := []struct {
+		name          string
+		processReturn []interface{}
+	}{
+		{
+			name:          "success",
+			processReturn: []interface{}{1, nil},
+		},
+		{
+			name:          "keeps running after an error",
+			processReturn: []interface{}{0, errors.NewCustomError(errors.InternalServerError, fmt.Errorf("error"))},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			calls := make(chan struct{}, 10)
+
+			var mockUseCase mockDataExportUseCase
+			mockUseCase.On("ProcessPendingExports").Return(test.processReturn...)
+			mockUseCase.On("DeleteExpiredExports").Return(0, nil).Run(func(mock.Arguments) {
+				select {
+				case calls <- struct{}{}:
+				default:
+				}
+			})
+
+			done := make(chan struct{})
+			go func() {
+				NewDataExportJob(&mockUseCase, time.Millisecond).Run(ctx)
+				close(done)
+			}()
+
+			// Runs right away and again after the interval, even when processing fails
+			for i := 0; i < 2; i++ {
+				select {
+				case <-calls:
+				case <-time.After(time.Second):
+					t.Fatal("job was not run")
+				}
+			}
+
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("job did not stop")
+			}
+			mockUseCase.AssertCalled(t, "ProcessPendingExports")
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package job
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chatapp/pkg/errors"
+)
+
+type UserPurgeUseCase interface {
+	PurgeDeletedUsers() (int64, *errors.CustomError)
+}
+
+// UserPurgeJob periodically purges the users deleted longer ago than the retention period.
+// Running it on several instances at once is safe, each run only deletes what is still there.
+type UserPurgeJob struct {
+	UserPurgeUseCase UserPurgeUseCase
+	Interval         time.Duration
+}
+
+func NewUserPurgeJob(userPurgeUseCase UserPurgeUseCase, interval time.Duration) *UserPurgeJob {
+	return &UserPurgeJob{
+		UserPurgeUseCase: userPurgeUseCase,
+		Interval:         interval,
+	}
+}
+
+// Run purges once right away and then at every interval until the context is done
+func (j *UserPurgeJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, customErr := j.UserPurgeUseCase.PurgeDeletedUsers(); customErr != nil {
+			log.Println("failed to purge deleted users:", customErr.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
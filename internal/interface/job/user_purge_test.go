@@ -0,0 +1,79 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"chatapp/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockUserPurgeUseCase struct {
+	mock.Mock
+}
+
+func (m *mockUserPurgeUseCase) PurgeDeletedUsers() (int64, *errors.CustomError) {
+	args := m.Called()
+	if args.Get(1) == nil {
+		return args.Get(0).(int64), nil
+	}
+	return args.Get(0).(int64), args.Get(1).(*errors.CustomError)
+}
+
+func TestUserPurgeJobRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockReturn []interface{}
+	}{
+		{
+			name:       "success",
+			mockReturn: []interface{}{int64(1), nil},
+		},
+		{
+			name:       "keeps running after an error",
+			mockReturn: []interface{}{int64(0), errors.NewCustomError(errors.InternalServerError, fmt.Errorf("error"))},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			calls := make(chan struct{}, 10)
+
+			var mockUseCase mockUserPurgeUseCase
+			mockUseCase.On("PurgeDeletedUsers").Return(test.mockReturn...).Run(func(mock.Arguments) {
+				select {
+				case calls <- struct{}{}:
+				default:
+				}
+			})
+
+			done := make(chan struct{})
+			go func() {
+				NewUserPurgeJob(&mockUseCase, time.Millisecond).Run(ctx)
+				close(done)
+			}()
+
+			// Purges right away and again after the interval
+			for i := 0; i < 2; i++ {
+				select {
+				case <-calls:
+				case <-time.After(time.Second):
+					t.Fatal("purge was not run")
+				}
+			}
+
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("job did not stop")
+			}
+			assert.GreaterOrEqual(t, len(mockUseCase.Calls), 2)
+		})
+	}
+}
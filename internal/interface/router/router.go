@@ -11,20 +11,38 @@ import (
 )
 
 type Handlers struct {
-	AuthHandler *handler.AuthHandler
-	UserHandler *handler.UserHandler
+	AuthHandler         *handler.AuthHandler
+	UserHandler         *handler.UserHandler
+	EmailChangeHandler  *handler.EmailChangeHandler
+	DataExportHandler   *handler.DataExportHandler
+	UserRelationHandler *handler.UserRelationHandler
 }
 
-func InitRouter(db *gorm.DB) *Handlers {
+func InitRouter(db *gorm.DB, mailer usecase.Mailer, exportStore usecase.ExportStore, appURL string) *Handlers {
 	userRepo := database.NewUserRepository(db)
-	userUseCase := usecase.NewUserUseCase(userRepo)
+	emailChangeRepo := database.NewEmailChangeRepository(db)
+	userUseCase := usecase.NewUserUseCase(userRepo, emailChangeRepo)
 	authHandler := handler.NewAuthHandler(userUseCase)
 
 	userHandler := handler.NewUserHandler(userUseCase)
 
+	emailChangeUseCase := usecase.NewEmailChangeUseCase(userRepo, emailChangeRepo, mailer, appURL)
+	emailChangeHandler := handler.NewEmailChangeHandler(emailChangeUseCase)
+
+	userRelationRepo := database.NewUserRelationRepository(db)
+	userRelationUseCase := usecase.NewUserRelationUseCase(userRepo, userRelationRepo)
+	userRelationHandler := handler.NewUserRelationHandler(userRelationUseCase)
+
+	dataExportRepo := database.NewDataExportRepository(db)
+	dataExportUseCase := usecase.NewDataExportUseCase(userRepo, emailChangeRepo, userRelationRepo, dataExportRepo, exportStore)
+	dataExportHandler := handler.NewDataExportHandler(dataExportUseCase)
+
 	handlers := &Handlers{
-		AuthHandler: authHandler,
-		UserHandler: userHandler,
+		AuthHandler:         authHandler,
+		UserHandler:         userHandler,
+		EmailChangeHandler:  emailChangeHandler,
+		DataExportHandler:   dataExportHandler,
+		UserRelationHandler: userRelationHandler,
 	}
 
 	return handlers
@@ -44,5 +62,22 @@ func (h *Handlers) SetUpRouter(e *echo.Echo) {
 	users.GET("/:id", h.UserHandler.RetrieveUser)
 	users.GET("/", h.UserHandler.ListUsers)
 	users.PUT("/:id", h.UserHandler.UpdateUserInfo)
+	users.PATCH("/:id", h.UserHandler.PatchUser)
+	users.PATCH("/:id/profile", h.UserHandler.UpdateProfile)
 	users.DELETE("/:id", h.UserHandler.DeleteUser)
+	users.POST("/restore", h.UserHandler.RestoreUser)
+	users.POST("/:id/email", h.EmailChangeHandler.RequestEmailChange)
+	users.POST("/email/confirm", h.EmailChangeHandler.ConfirmEmailChange)
+	users.POST("/email/revert", h.EmailChangeHandler.RevertEmailChange)
+	users.POST("/:id/export", h.DataExportHandler.RequestExport)
+	users.GET("/:id/export/:exportId", h.DataExportHandler.RetrieveExport)
+	users.GET("/:id/blocks", h.UserRelationHandler.ListBlockedUsers)
+	users.PUT("/:id/blocks/:targetId", h.UserRelationHandler.BlockUser)
+	users.DELETE("/:id/blocks/:targetId", h.UserRelationHandler.UnblockUser)
+	users.GET("/:id/mutes", h.UserRelationHandler.ListMutedUsers)
+	users.PUT("/:id/mutes/:targetId", h.UserRelationHandler.MuteUser)
+	users.DELETE("/:id/mutes/:targetId", h.UserRelationHandler.UnmuteUser)
+
+	exports := v1.Group("/exports")
+	exports.POST("/:id/download", h.DataExportHandler.DownloadExport)
 }
@@ -0,0 +1,416 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"time"
+
+	"chatapp/internal/domain/entity"
+	"chatapp/pkg/errors"
+)
+
+// DataExportFormatVersion is the version of the export archive layout described in docs/export-format.md.
+// Bump it whenever a file is removed or a field changes meaning, adding fields does not need a new version.
+const DataExportFormatVersion = 1
+
+// DataExportRepository is a repository for the data export entity
+type DataExportRepository interface {
+	Create(export *entity.DataExport) (*entity.DataExport, error)
+	FindByID(id string) (*entity.DataExport, error)
+	ClaimPending() (*entity.DataExport, error)
+	FindExpired(now time.Time) ([]*entity.DataExport, error)
+	Update(export *entity.DataExport) error
+}
+
+// ExportStore keeps the finished export archives until they expire. Every instance must see
+// the same files, and Open fails with an error wrapping fs.ErrNotExist when a file is gone.
+type ExportStore interface {
+	Save(name string, data []byte) (string, error)
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// DataExportUseCase is a use case for exporting the personal data of a user
+type DataExportUseCase struct {
+	UserRepo        UserRepository
+	EmailChangeRepo EmailChangeRepository
+	RelationRepo    UserRelationRepository
+	DataExportRepo  DataExportRepository
+	Store           ExportStore
+}
+
+// DataExportResponse is a response for a data export.
+// DownloadToken is only returned when the export is requested, it is needed to download the archive.
+type DataExportResponse struct {
+	ID            uint
+	Status        string
+	DownloadToken string `json:",omitempty"`
+	ExpiresAt     *time.Time
+}
+
+// RequestExportInput is an input for requesting a data export
+type RequestExportInput struct {
+	UserID   string
+	Password string
+}
+
+// exportManifest is manifest.json of the archive
+type exportManifest struct {
+	FormatVersion int       `json:"format_version"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	UserID        uint      `json:"user_id"`
+	Files         []string  `json:"files"`
+}
+
+// exportProfile is profile.json of the archive
+type exportProfile struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Handle      string    `json:"handle"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	Timezone    string    `json:"timezone"`
+	Locale      string    `json:"locale"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// exportEmailChange is an entry of email_changes.json of the archive
+type exportEmailChange struct {
+	OldEmail    string     `json:"old_email"`
+	NewEmail    string     `json:"new_email"`
+	RequestedAt time.Time  `json:"requested_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	RevertedAt  *time.Time `json:"reverted_at"`
+	CancelledAt *time.Time `json:"cancelled_at"`
+}
+
+// exportRelation is an entry of blocks.json and mutes.json of the archive.
+// The handle is empty when the other user deleted their account.
+type exportRelation struct {
+	UserID uint      `json:"user_id"`
+	Handle string    `json:"handle"`
+	Since  time.Time `json:"since"`
+}
+
+// exportData is everything held about a user, as read for an export
+type exportData struct {
+	user      *entity.User
+	changes   []*entity.EmailChange
+	relations []*entity.UserRelation
+	// handles are the current handles of the users the relations point to
+	handles map[uint]string
+}
+
+// NewDataExportUseCase creates a new data export use case
+func NewDataExportUseCase(userRepo UserRepository, emailChangeRepo EmailChangeRepository, relationRepo UserRelationRepository, dataExportRepo DataExportRepository, store ExportStore) *DataExportUseCase {
+	return &DataExportUseCase{
+		UserRepo:        userRepo,
+		EmailChangeRepo: emailChangeRepo,
+		RelationRepo:    relationRepo,
+		DataExportRepo:  dataExportRepo,
+		Store:           store,
+	}
+}
+
+// NewRequestExportInput creates a new input for requesting a data export
+func NewRequestExportInput(userID, password string) *RequestExportInput {
+	return &RequestExportInput{
+		UserID:   userID,
+		Password: password,
+	}
+}
+
+func newDataExportResponse(export *entity.DataExport) *DataExportResponse {
+	return &DataExportResponse{
+		ID:        export.ID,
+		Status:    string(export.Status),
+		ExpiresAt: export.ExpiresAt,
+	}
+}
+
+// RequestExport queues an export of the data of the user, it is built in the background
+func (u *DataExportUseCase) RequestExport(input *RequestExportInput) (*DataExportResponse, *errors.CustomError) {
+	log.Println("RequestExport:", input.UserID)
+
+	user, err := u.UserRepo.FindByID(input.UserID)
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if user == nil {
+		return nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("user not found"))
+	}
+
+	// The archive holds the email and history of the account, so the password is asked again
+	if !user.CheckPassword(input.Password) {
+		return nil, errors.NewCustomError(errors.InvalidCredentials, fmt.Errorf("invalid credentials"))
+	}
+
+	export, token, err := entity.NewDataExport(user.ID)
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	export, err = u.DataExportRepo.Create(export)
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	response := newDataExportResponse(export)
+	response.DownloadToken = token
+
+	return response, nil
+}
+
+// ReadExport returns the status of an export of the user
+func (u *DataExportUseCase) ReadExport(userID, exportID string) (*DataExportResponse, *errors.CustomError) {
+	log.Println("ReadExport:", userID, exportID)
+
+	export, err := u.DataExportRepo.FindByID(exportID)
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+	// Exports of other users are reported as missing so their IDs cannot be probed
+	if export == nil || fmt.Sprint(export.UserID) != userID {
+		return nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("export not found"))
+	}
+
+	return newDataExportResponse(export), nil
+}
+
+// OpenExportDownload opens the archive of a ready export with its download token.
+// The caller must close the returned reader.
+func (u *DataExportUseCase) OpenExportDownload(exportID, token string) (io.ReadCloser, string, *errors.CustomError) {
+	log.Println("OpenExportDownload:", exportID)
+
+	export, err := u.DataExportRepo.FindByID(exportID)
+	if err != nil {
+		return nil, "", errors.NewCustomError(errors.InternalServerError, err)
+	}
+	// A wrong token, a pending and an expired export all look the same to the client
+	if export == nil || !export.CanDownload(token, time.Now()) {
+		return nil, "", errors.NewCustomError(errors.NotFound, fmt.Errorf("export not found"))
+	}
+
+	file, err := u.Store.Open(export.FileKey)
+	if stderrors.Is(err, fs.ErrNotExist) {
+		// The archive was lost, the export is reported as expired so that the user requests a new one
+		log.Println("data export file missing:", export.ID, err)
+		export.Expire()
+		if err := u.DataExportRepo.Update(export); err != nil {
+			return nil, "", errors.NewCustomError(errors.InternalServerError, err)
+		}
+		return nil, "", errors.NewCustomError(errors.NotFound, fmt.Errorf("export not found"))
+	}
+	if err != nil {
+		return nil, "", errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	return file, exportFileName(export), nil
+}
+
+// ProcessPendingExports builds the archives of every pending export and returns how many were processed
+func (u *DataExportUseCase) ProcessPendingExports() (int, *errors.CustomError) {
+	processed := 0
+	for {
+		export, err := u.DataExportRepo.ClaimPending()
+		if err != nil {
+			return processed, errors.NewCustomError(errors.InternalServerError, err)
+		}
+		if export == nil {
+			return processed, nil
+		}
+
+		if err := u.processExport(export); err != nil {
+			log.Println("failed to build data export:", export.ID, err)
+			export.Fail(err)
+		}
+
+		if err := u.DataExportRepo.Update(export); err != nil {
+			return processed, errors.NewCustomError(errors.InternalServerError, err)
+		}
+		processed++
+	}
+}
+
+// DeleteExpiredExports deletes the archives of the exports past their download period
+func (u *DataExportUseCase) DeleteExpiredExports() (int, *errors.CustomError) {
+	exports, err := u.DataExportRepo.FindExpired(time.Now())
+	if err != nil {
+		return 0, errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	for i, export := range exports {
+		if err := u.Store.Delete(export.FileKey); err != nil {
+			return i, errors.NewCustomError(errors.InternalServerError, err)
+		}
+
+		export.Expire()
+		if err := u.DataExportRepo.Update(export); err != nil {
+			return i, errors.NewCustomError(errors.InternalServerError, err)
+		}
+	}
+
+	if len(exports) > 0 {
+		log.Println("Deleted expired data exports:", len(exports))
+	}
+
+	return len(exports), nil
+}
+
+func (u *DataExportUseCase) processExport(export *entity.DataExport) error {
+	user, err := u.UserRepo.FindByID(fmt.Sprint(export.UserID))
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	data := &exportData{user: user, handles: map[uint]string{}}
+	data.changes, err = u.EmailChangeRepo.FindByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+	data.relations, err = u.RelationRepo.FindByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, kind := range []entity.UserRelationKind{entity.UserRelationBlock, entity.UserRelationMute} {
+		targets, err := u.RelationRepo.FindTargets(user.ID, kind)
+		if err != nil {
+			return err
+		}
+		for _, target := range targets {
+			data.handles[target.ID] = target.Handle
+		}
+	}
+
+	archive, err := buildExportArchive(data, time.Now())
+	if err != nil {
+		return err
+	}
+
+	fileKey, err := u.Store.Save(exportFileName(export), archive)
+	if err != nil {
+		return err
+	}
+
+	return export.Complete(fileKey, time.Now())
+}
+
+// buildExportArchive builds the ZIP archive of the data of the user
+func buildExportArchive(data *exportData, now time.Time) ([]byte, error) {
+	user := data.user
+	emailChanges := make([]exportEmailChange, 0, len(data.changes))
+	for _, change := range data.changes {
+		var cancelledAt *time.Time
+		if change.DeletedAt.Valid {
+			cancelledAt = &change.DeletedAt.Time
+		}
+		emailChanges = append(emailChanges, exportEmailChange{
+			OldEmail:    change.OldEmail,
+			NewEmail:    change.NewEmail,
+			RequestedAt: change.CreatedAt,
+			ConfirmedAt: change.ConfirmedAt,
+			RevertedAt:  change.RevertedAt,
+			CancelledAt: cancelledAt,
+		})
+	}
+
+	relations := map[entity.UserRelationKind][]exportRelation{
+		entity.UserRelationBlock: {},
+		entity.UserRelationMute:  {},
+	}
+	for _, relation := range data.relations {
+		relations[relation.Kind] = append(relations[relation.Kind], exportRelation{
+			UserID: relation.TargetID,
+			Handle: data.handles[relation.TargetID],
+			Since:  relation.CreatedAt,
+		})
+	}
+
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{
+			name: "profile.json",
+			content: exportProfile{
+				ID:          user.ID,
+				Name:        user.Name,
+				Handle:      user.Handle,
+				Email:       user.Email,
+				DisplayName: user.DisplayName,
+				Bio:         user.Bio,
+				Timezone:    user.Timezone,
+				Locale:      user.Locale,
+				CreatedAt:   user.CreatedAt,
+				UpdatedAt:   user.UpdatedAt,
+			},
+		},
+		{
+			name:    "email_changes.json",
+			content: emailChanges,
+		},
+		{
+			name:    "blocks.json",
+			content: relations[entity.UserRelationBlock],
+		},
+		{
+			name:    "mutes.json",
+			content: relations[entity.UserRelationMute],
+		},
+	}
+
+	manifest := exportManifest{
+		FormatVersion: DataExportFormatVersion,
+		GeneratedAt:   now.UTC(),
+		UserID:        user.ID,
+	}
+	for _, file := range files {
+		manifest.Files = append(manifest.Files, file.name)
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	if err := writeExportFile(archive, "manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := writeExportFile(archive, file.name, file.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close export archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeExportFile(archive *zip.Writer, name string, content interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to export archive: %w", name, err)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(content); err != nil {
+		return fmt.Errorf("failed to write %s to export archive: %w", name, err)
+	}
+
+	return nil
+}
+
+func exportFileName(export *entity.DataExport) string {
+	return fmt.Sprintf("chatapp-export-%d.zip", export.ID)
+}
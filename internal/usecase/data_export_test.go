@@ -0,0 +1,491 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"chatapp/internal/domain/entity"
+	customerrors "chatapp/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type mockDataExportRepo struct {
+	mock.Mock
+}
+
+func (m *mockDataExportRepo) Create(export *entity.DataExport) (*entity.DataExport, error) {
+	args := m.Called(export)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.DataExport), args.Error(1)
+}
+
+func (m *mockDataExportRepo) FindByID(id string) (*entity.DataExport, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.DataExport), args.Error(1)
+}
+
+func (m *mockDataExportRepo) ClaimPending() (*entity.DataExport, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.DataExport), args.Error(1)
+}
+
+func (m *mockDataExportRepo) FindExpired(now time.Time) ([]*entity.DataExport, error) {
+	args := m.Called(now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.DataExport), args.Error(1)
+}
+
+func (m *mockDataExportRepo) Update(export *entity.DataExport) error {
+	args := m.Called(export)
+	return args.Error(0)
+}
+
+type mockExportStore struct {
+	mock.Mock
+}
+
+func (m *mockExportStore) Save(name string, data []byte) (string, error) {
+	args := m.Called(name, data)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockExportStore) Open(key string) (io.ReadCloser, error) {
+	args := m.Called(key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
+
+func (m *mockExportStore) Delete(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func TestRequestExport(t *testing.T) {
+	user, _ := entity.NewUser("test", "test", "test@test.com", "password")
+	user.ID = 1
+
+	tests := []struct {
+		name             string
+		in               *RequestExportInput
+		findMockReturn   []interface{}
+		createMockReturn []interface{}
+		wantErrType      customerrors.CustomErrorType
+		wantErr          bool
+	}{
+		{
+			name:             "success",
+			in:               &RequestExportInput{UserID: "1", Password: "password"},
+			findMockReturn:   []interface{}{user, nil},
+			createMockReturn: []interface{}{&entity.DataExport{Model: gorm.Model{ID: 1}, Status: entity.DataExportPending}, nil},
+			wantErr:          false,
+		},
+		{
+			name:           "error when user not found",
+			in:             &RequestExportInput{UserID: "1", Password: "password"},
+			findMockReturn: []interface{}{nil, nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when password is wrong",
+			in:             &RequestExportInput{UserID: "1", Password: "wrong"},
+			findMockReturn: []interface{}{user, nil},
+			wantErrType:    customerrors.InvalidCredentials,
+			wantErr:        true,
+		},
+		{
+			name:             "error when creating export",
+			in:               &RequestExportInput{UserID: "1", Password: "password"},
+			findMockReturn:   []interface{}{user, nil},
+			createMockReturn: []interface{}{nil, errors.New("error")},
+			wantErrType:      customerrors.InternalServerError,
+			wantErr:          true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserRepo mockUserRepo
+			var mockExportRepo mockDataExportRepo
+			mockUserRepo.On("FindByID", test.in.UserID).Return(test.findMockReturn...)
+			if test.createMockReturn != nil {
+				mockExportRepo.On("Create", mock.Anything).Return(test.createMockReturn...)
+			}
+
+			u := NewDataExportUseCase(&mockUserRepo, &mockEmailChangeRepo{}, &mockUserRelationRepo{}, &mockExportRepo, &mockExportStore{})
+			res, err := u.RequestExport(test.in)
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+				assert.Nil(t, res)
+			} else {
+				assert.Nil(t, err)
+				created := mockExportRepo.Calls[0].Arguments.Get(0).(*entity.DataExport)
+				assert.Equal(t, uint(1), created.UserID)
+				assert.Equal(t, created.DownloadTokenHash, entity.HashToken(res.DownloadToken))
+				assert.Equal(t, "pending", res.Status)
+				mockUserRepo.AssertExpectations(t)
+				mockExportRepo.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestReadExport(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		findMockReturn []interface{}
+		wantErrType    customerrors.CustomErrorType
+		wantErr        bool
+	}{
+		{
+			name:           "success",
+			userID:         "1",
+			findMockReturn: []interface{}{&entity.DataExport{Model: gorm.Model{ID: 1}, UserID: 1, Status: entity.DataExportReady}, nil},
+			wantErr:        false,
+		},
+		{
+			name:           "error when export not found",
+			userID:         "1",
+			findMockReturn: []interface{}{nil, nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when export belongs to another user",
+			userID:         "2",
+			findMockReturn: []interface{}{&entity.DataExport{Model: gorm.Model{ID: 1}, UserID: 1}, nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when finding export",
+			userID:         "1",
+			findMockReturn: []interface{}{nil, errors.New("error")},
+			wantErrType:    customerrors.InternalServerError,
+			wantErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockExportRepo mockDataExportRepo
+			mockExportRepo.On("FindByID", "1").Return(test.findMockReturn...)
+
+			u := NewDataExportUseCase(&mockUserRepo{}, &mockEmailChangeRepo{}, &mockUserRelationRepo{}, &mockExportRepo, &mockExportStore{})
+			res, err := u.ReadExport(test.userID, "1")
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, &DataExportResponse{ID: 1, Status: "ready"}, res)
+			}
+		})
+	}
+}
+
+func TestOpenExportDownload(t *testing.T) {
+	newExport := func(status entity.DataExportStatus, expiresAt time.Time) *entity.DataExport {
+		return &entity.DataExport{
+			Model:             gorm.Model{ID: 1},
+			UserID:            1,
+			Status:            status,
+			DownloadTokenHash: entity.HashToken("token"),
+			FileKey:           "chatapp-export-1.zip",
+			ExpiresAt:         &expiresAt,
+		}
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		findMockReturn []interface{}
+		openMockReturn []interface{}
+		wantStatus     entity.DataExportStatus
+		wantErrType    customerrors.CustomErrorType
+		wantErr        bool
+	}{
+		{
+			name:           "success",
+			token:          "token",
+			findMockReturn: []interface{}{newExport(entity.DataExportReady, time.Now().Add(time.Hour)), nil},
+			wantErr:        false,
+		},
+		{
+			name:           "error when file is missing",
+			token:          "token",
+			findMockReturn: []interface{}{newExport(entity.DataExportReady, time.Now().Add(time.Hour)), nil},
+			openMockReturn: []interface{}{nil, fmt.Errorf("failed to open file: %w", fs.ErrNotExist)},
+			wantStatus:     entity.DataExportExpired,
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when opening file",
+			token:          "token",
+			findMockReturn: []interface{}{newExport(entity.DataExportReady, time.Now().Add(time.Hour)), nil},
+			openMockReturn: []interface{}{nil, errors.New("permission denied")},
+			wantStatus:     entity.DataExportReady,
+			wantErrType:    customerrors.InternalServerError,
+			wantErr:        true,
+		},
+		{
+			name:           "error when token is wrong",
+			token:          "wrong",
+			findMockReturn: []interface{}{newExport(entity.DataExportReady, time.Now().Add(time.Hour)), nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when export is expired",
+			token:          "token",
+			findMockReturn: []interface{}{newExport(entity.DataExportReady, time.Now().Add(-time.Hour)), nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when export is not ready",
+			token:          "token",
+			findMockReturn: []interface{}{newExport(entity.DataExportPending, time.Now().Add(time.Hour)), nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when export not found",
+			token:          "token",
+			findMockReturn: []interface{}{nil, nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockExportRepo mockDataExportRepo
+			var mockStore mockExportStore
+			mockExportRepo.On("FindByID", "1").Return(test.findMockReturn...)
+			if test.openMockReturn == nil {
+				test.openMockReturn = []interface{}{io.NopCloser(strings.NewReader("zip")), nil}
+			}
+			mockStore.On("Open", "chatapp-export-1.zip").Return(test.openMockReturn...)
+			mockExportRepo.On("Update", mock.Anything).Return(nil)
+
+			u := NewDataExportUseCase(&mockUserRepo{}, &mockEmailChangeRepo{}, &mockUserRelationRepo{}, &mockExportRepo, &mockStore)
+			file, name, err := u.OpenExportDownload("1", test.token)
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+				if test.wantStatus == "" {
+					mockStore.AssertNotCalled(t, "Open", mock.Anything)
+				} else {
+					assert.Equal(t, test.wantStatus, test.findMockReturn[0].(*entity.DataExport).Status)
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, "chatapp-export-1.zip", name)
+				data, _ := io.ReadAll(file)
+				assert.Equal(t, "zip", string(data))
+			}
+		})
+	}
+}
+
+func TestProcessPendingExports(t *testing.T) {
+	user, _ := entity.NewUser("test", "test", "test@test.com", "password")
+	user.ID = 1
+	confirmedAt := time.Now()
+	changes := []*entity.EmailChange{
+		{UserID: 1, OldEmail: "old@test.com", NewEmail: "test@test.com", ConfirmedAt: &confirmedAt},
+	}
+	target := &entity.User{Model: gorm.Model{ID: 2}, Handle: "target"}
+	relations := []*entity.UserRelation{{UserID: 1, TargetID: 2, Kind: entity.UserRelationBlock}}
+
+	tests := []struct {
+		name           string
+		findMockReturn []interface{}
+		saveMockReturn []interface{}
+		wantStatus     entity.DataExportStatus
+	}{
+		{
+			name:           "success",
+			findMockReturn: []interface{}{user, nil},
+			saveMockReturn: []interface{}{"chatapp-export-1.zip", nil},
+			wantStatus:     entity.DataExportReady,
+		},
+		{
+			name:           "failed when user not found",
+			findMockReturn: []interface{}{nil, nil},
+			wantStatus:     entity.DataExportFailed,
+		},
+		{
+			name:           "failed when saving archive",
+			findMockReturn: []interface{}{user, nil},
+			saveMockReturn: []interface{}{"", errors.New("disk full")},
+			wantStatus:     entity.DataExportFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserRepo mockUserRepo
+			var mockChangeRepo mockEmailChangeRepo
+			var mockRelationRepo mockUserRelationRepo
+			var mockExportRepo mockDataExportRepo
+			var mockStore mockExportStore
+			export := &entity.DataExport{Model: gorm.Model{ID: 1}, UserID: 1, Status: entity.DataExportProcessing}
+			mockExportRepo.On("ClaimPending").Return(export, nil).Once()
+			mockExportRepo.On("ClaimPending").Return(nil, nil).Once()
+			mockExportRepo.On("Update", export).Return(nil)
+			mockUserRepo.On("FindByID", "1").Return(test.findMockReturn...)
+			mockChangeRepo.On("FindByUserID", uint(1)).Return(changes, nil)
+			mockRelationRepo.On("FindByUserID", uint(1)).Return(relations, nil)
+			mockRelationRepo.On("FindTargets", uint(1), entity.UserRelationBlock).Return([]*entity.User{target}, nil)
+			mockRelationRepo.On("FindTargets", uint(1), entity.UserRelationMute).Return([]*entity.User{}, nil)
+			if test.saveMockReturn != nil {
+				mockStore.On("Save", "chatapp-export-1.zip", mock.Anything).Return(test.saveMockReturn...)
+			}
+
+			u := NewDataExportUseCase(&mockUserRepo, &mockChangeRepo, &mockRelationRepo, &mockExportRepo, &mockStore)
+			processed, err := u.ProcessPendingExports()
+			assert.Nil(t, err)
+			assert.Equal(t, 1, processed)
+			assert.Equal(t, test.wantStatus, export.Status)
+			if test.wantStatus == entity.DataExportReady {
+				assert.Equal(t, "chatapp-export-1.zip", export.FileKey)
+				assert.NotNil(t, export.ExpiresAt)
+			} else {
+				assert.NotEmpty(t, export.Error)
+			}
+			mockExportRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBuildExportArchive(t *testing.T) {
+	user, _ := entity.NewUser("test", "test", "test@test.com", "password")
+	user.ID = 1
+	changes := []*entity.EmailChange{
+		{
+			Model:    gorm.Model{DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}},
+			UserID:   1,
+			OldEmail: "test@test.com",
+			NewEmail: "cancelled@test.com",
+		},
+		{UserID: 1, OldEmail: "old@test.com", NewEmail: "test@test.com"},
+	}
+
+	relations := []*entity.UserRelation{
+		{UserID: 1, TargetID: 2, Kind: entity.UserRelationBlock},
+		{UserID: 1, TargetID: 3, Kind: entity.UserRelationMute},
+		{UserID: 1, TargetID: 4, Kind: entity.UserRelationMute},
+	}
+	handles := map[uint]string{2: "blocked", 3: "muted"}
+
+	data, err := buildExportArchive(&exportData{user: user, changes: changes, relations: relations, handles: handles}, time.Now())
+	assert.NoError(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+
+	contents := map[string]interface{}{}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		r, _ := file.Open()
+		var content interface{}
+		assert.NoError(t, json.NewDecoder(r).Decode(&content))
+		r.Close()
+		contents[file.Name] = content
+	}
+
+	assert.Equal(t, []string{"manifest.json", "profile.json", "email_changes.json", "blocks.json", "mutes.json"}, names)
+	manifest := contents["manifest.json"].(map[string]interface{})
+	assert.Equal(t, float64(DataExportFormatVersion), manifest["format_version"])
+	assert.Equal(t, []interface{}{"profile.json", "email_changes.json", "blocks.json", "mutes.json"}, manifest["files"])
+	profile := contents["profile.json"].(map[string]interface{})
+	assert.Equal(t, "test@test.com", profile["email"])
+	assert.NotContains(t, profile, "password")
+	emailChanges := contents["email_changes.json"].([]interface{})
+	assert.Equal(t, 2, len(emailChanges))
+	assert.NotNil(t, emailChanges[0].(map[string]interface{})["cancelled_at"])
+	assert.Nil(t, emailChanges[1].(map[string]interface{})["cancelled_at"])
+	blocks := contents["blocks.json"].([]interface{})
+	assert.Equal(t, 1, len(blocks))
+	assert.Equal(t, "blocked", blocks[0].(map[string]interface{})["handle"])
+	mutes := contents["mutes.json"].([]interface{})
+	assert.Equal(t, 2, len(mutes))
+	assert.Equal(t, float64(3), mutes[0].(map[string]interface{})["user_id"])
+	assert.Equal(t, "", mutes[1].(map[string]interface{})["handle"])
+}
+
+func TestDeleteExpiredExports(t *testing.T) {
+	tests := []struct {
+		name             string
+		deleteMockReturn error
+		wantDeleted      int
+		wantErr          bool
+	}{
+		{
+			name:             "success",
+			deleteMockReturn: nil,
+			wantDeleted:      1,
+			wantErr:          false,
+		},
+		{
+			name:             "error when deleting file",
+			deleteMockReturn: errors.New("error"),
+			wantDeleted:      0,
+			wantErr:          true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockExportRepo mockDataExportRepo
+			var mockStore mockExportStore
+			expiresAt := time.Now().Add(-time.Hour)
+			export := &entity.DataExport{Model: gorm.Model{ID: 1}, Status: entity.DataExportReady, FileKey: "chatapp-export-1.zip", ExpiresAt: &expiresAt}
+			mockExportRepo.On("FindExpired", mock.Anything).Return([]*entity.DataExport{export}, nil)
+			mockExportRepo.On("Update", export).Return(nil)
+			mockStore.On("Delete", "chatapp-export-1.zip").Return(test.deleteMockReturn)
+
+			u := NewDataExportUseCase(&mockUserRepo{}, &mockEmailChangeRepo{}, &mockUserRelationRepo{}, &mockExportRepo, &mockStore)
+			deleted, err := u.DeleteExpiredExports()
+			assert.Equal(t, test.wantDeleted, deleted)
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, customerrors.InternalServerError, err.Type)
+				assert.Equal(t, entity.DataExportReady, export.Status)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, entity.DataExportExpired, export.Status)
+				assert.Empty(t, export.FileKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"chatapp/internal/domain/entity"
+	"chatapp/pkg/errors"
+)
+
+// EmailChangeRepository is a repository for the email change entity
+type EmailChangeRepository interface {
+	Create(change *entity.EmailChange) (*entity.EmailChange, error)
+	FindByConfirmTokenHash(tokenHash string) (*entity.EmailChange, error)
+	FindByRevertTokenHash(tokenHash string) (*entity.EmailChange, error)
+	FindByUserID(userID uint) ([]*entity.EmailChange, error)
+	FindRevertableByOldEmail(email string, now time.Time) (*entity.EmailChange, error)
+	ConfirmWithUser(change *entity.EmailChange, user *entity.User) error
+	RevertWithUser(change *entity.EmailChange, user *entity.User) error
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// EmailChangeUseCase is a use case for changing the email of a user
+type EmailChangeUseCase struct {
+	UserRepo        UserRepository
+	EmailChangeRepo EmailChangeRepository
+	Mailer          Mailer
+	// AppURL is the base URL of the client, links in emails point to it
+	AppURL string
+}
+
+// RequestEmailChangeInput is an input for requesting an email change
+type RequestEmailChangeInput struct {
+	UserID   string
+	NewEmail string
+	Password string
+}
+
+// NewEmailChangeUseCase creates a new email change use case
+func NewEmailChangeUseCase(userRepo UserRepository, emailChangeRepo EmailChangeRepository, mailer Mailer, appURL string) *EmailChangeUseCase {
+	return &EmailChangeUseCase{
+		UserRepo:        userRepo,
+		EmailChangeRepo: emailChangeRepo,
+		Mailer:          mailer,
+		AppURL:          appURL,
+	}
+}
+
+// NewRequestEmailChangeInput creates a new input for requesting an email change
+func NewRequestEmailChangeInput(userID, newEmail, password string) *RequestEmailChangeInput {
+	return &RequestEmailChangeInput{
+		UserID:   userID,
+		NewEmail: newEmail,
+		Password: password,
+	}
+}
+
+// RequestEmailChange sends a confirmation link to the new address, the email is not changed until it is used
+func (u *EmailChangeUseCase) RequestEmailChange(input *RequestEmailChangeInput) *errors.CustomError {
+	log.Println("RequestEmailChange:", input.UserID)
+
+	user, err := u.UserRepo.FindByID(input.UserID)
+	if err != nil {
+		return errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if user == nil {
+		return errors.NewCustomError(errors.NotFound, fmt.Errorf("user not found"))
+	}
+
+	// The password is asked again so that a stolen session alone cannot take over the account
+	if !user.CheckPassword(input.Password) {
+		return errors.NewCustomError(errors.InvalidCredentials, fmt.Errorf("invalid credentials"))
+	}
+
+	change, token, err := entity.NewEmailChange(user, input.NewEmail, time.Now())
+	if err != nil {
+		return errors.NewValidationError(map[string]string{"email": err.Error()})
+	}
+
+	if customErr := u.checkEmailAvailable(change.NewEmail, user.ID); customErr != nil {
+		return customErr
+	}
+
+	if _, err := u.EmailChangeRepo.Create(change); err != nil {
+		return errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	body := fmt.Sprintf(
+		"Confirm your new email address by opening this link within %s:\n\n%s\n\nIf you did not ask for this, you can ignore this email.",
+		formatTTL(entity.EmailChangeConfirmTTL), u.link("/email/confirm", token),
+	)
+	if err := u.Mailer.Send(change.NewEmail, "Confirm your new email address", body); err != nil {
+		return errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange switches the user to the new email and tells the old address how to revert it
+func (u *EmailChangeUseCase) ConfirmEmailChange(token string) (*UserResponse, *errors.CustomError) {
+	log.Println("ConfirmEmailChange")
+
+	change, err := u.EmailChangeRepo.FindByConfirmTokenHash(entity.HashToken(token))
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if change == nil {
+		return nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("email change not found"))
+	}
+
+	user, customErr := u.findUser(change.UserID)
+	if customErr != nil {
+		return nil, customErr
+	}
+
+	if customErr := u.checkEmailAvailable(change.NewEmail, user.ID); customErr != nil {
+		return nil, customErr
+	}
+
+	revertToken, err := change.Confirm(user, time.Now())
+	if err != nil {
+		return nil, errors.NewCustomError(errors.BadRequest, err)
+	}
+
+	if err := u.EmailChangeRepo.ConfirmWithUser(change, user); err != nil {
+		return nil, repositoryError(err)
+	}
+
+	body := fmt.Sprintf(
+		"The email address of your account was changed to %s.\n\nIf you did not do this, restore your previous address within %s by opening this link:\n\n%s",
+		change.NewEmail, formatTTL(entity.EmailChangeRevertTTL), u.link("/email/revert", revertToken),
+	)
+	if err := u.Mailer.Send(change.OldEmail, "Your email address was changed", body); err != nil {
+		// The change is already saved, failing here would only hide that from the user
+		log.Println("failed to notify old email address:", err)
+	}
+
+	return newUserResponse(user), nil
+}
+
+// RevertEmailChange switches the user back to the email it had before the change
+func (u *EmailChangeUseCase) RevertEmailChange(token string) (*UserResponse, *errors.CustomError) {
+	log.Println("RevertEmailChange")
+
+	change, err := u.EmailChangeRepo.FindByRevertTokenHash(entity.HashToken(token))
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if change == nil {
+		return nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("email change not found"))
+	}
+
+	user, customErr := u.findUser(change.UserID)
+	if customErr != nil {
+		return nil, customErr
+	}
+
+	if customErr := u.checkEmailAvailable(change.OldEmail, user.ID); customErr != nil {
+		return nil, customErr
+	}
+
+	if err := change.Revert(user, time.Now()); err != nil {
+		return nil, errors.NewCustomError(errors.BadRequest, err)
+	}
+
+	if err := u.EmailChangeRepo.RevertWithUser(change, user); err != nil {
+		return nil, repositoryError(err)
+	}
+
+	return newUserResponse(user), nil
+}
+
+func (u *EmailChangeUseCase) findUser(userID uint) (*entity.User, *errors.CustomError) {
+	user, err := u.UserRepo.FindByID(fmt.Sprint(userID))
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if user == nil {
+		return nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("user not found"))
+	}
+	return user, nil
+}
+
+// checkEmailAvailable returns a conflict when another user has the email or holds it for a revert
+func (u *EmailChangeUseCase) checkEmailAvailable(email string, userID uint) *errors.CustomError {
+	other, err := u.UserRepo.FindByEmail(email)
+	if err != nil {
+		return errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if other != nil && other.ID != userID {
+		return errors.NewCustomError(errors.Conflict, fmt.Errorf("email already taken"))
+	}
+	return checkEmailNotReserved(u.EmailChangeRepo, email, userID)
+}
+
+// checkEmailNotReserved returns a conflict when the email is the old address of another user
+// whose change can still be reverted. Otherwise whoever took over an account could change its
+// email and register the old address elsewhere, and the owner could never revert.
+func checkEmailNotReserved(repo EmailChangeRepository, email string, userID uint) *errors.CustomError {
+	change, err := repo.FindRevertableByOldEmail(email, time.Now())
+	if err != nil {
+		return errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if change != nil && change.UserID != userID {
+		return errors.NewCustomError(errors.Conflict, fmt.Errorf("email already taken"))
+	}
+	return nil
+}
+
+// formatTTL writes how long a link lasts the way people read it, as "24 hours" or "7 days"
+func formatTTL(ttl time.Duration) string {
+	count, unit := int(ttl.Hours()), "hour"
+	if ttl > 24*time.Hour && ttl%(24*time.Hour) == 0 {
+		count, unit = int(ttl.Hours()/24), "day"
+	}
+	if count != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", count, unit)
+}
+
+func (u *EmailChangeUseCase) link(path, token string) string {
+	return u.AppURL + path + "?token=" + url.QueryEscape(token)
+}
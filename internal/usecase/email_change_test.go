@@ -0,0 +1,406 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"chatapp/internal/domain/entity"
+	customerrors "chatapp/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type mockEmailChangeRepo struct {
+	mock.Mock
+}
+
+func (m *mockEmailChangeRepo) Create(change *entity.EmailChange) (*entity.EmailChange, error) {
+	args := m.Called(change)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.EmailChange), args.Error(1)
+}
+
+func (m *mockEmailChangeRepo) FindByConfirmTokenHash(tokenHash string) (*entity.EmailChange, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.EmailChange), args.Error(1)
+}
+
+func (m *mockEmailChangeRepo) FindByRevertTokenHash(tokenHash string) (*entity.EmailChange, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.EmailChange), args.Error(1)
+}
+
+func (m *mockEmailChangeRepo) FindByUserID(userID uint) ([]*entity.EmailChange, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.EmailChange), args.Error(1)
+}
+
+func (m *mockEmailChangeRepo) FindRevertableByOldEmail(email string, now time.Time) (*entity.EmailChange, error) {
+	args := m.Called(email, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.EmailChange), args.Error(1)
+}
+
+func (m *mockEmailChangeRepo) ConfirmWithUser(change *entity.EmailChange, user *entity.User) error {
+	args := m.Called(change, user)
+	return args.Error(0)
+}
+
+func (m *mockEmailChangeRepo) RevertWithUser(change *entity.EmailChange, user *entity.User) error {
+	args := m.Called(change, user)
+	return args.Error(0)
+}
+
+type mockMailer struct {
+	mock.Mock
+}
+
+func (m *mockMailer) Send(to, subject, body string) error {
+	args := m.Called(to, subject, body)
+	return args.Error(0)
+}
+
+// tokenFromBody extracts the token of the link in an email body
+func tokenFromBody(body string) string {
+	i := strings.Index(body, "?token=")
+	rest := body[i+len("?token="):]
+	if j := strings.IndexAny(rest, "\n "); j >= 0 {
+		rest = rest[:j]
+	}
+	token, _ := url.QueryUnescape(rest)
+	return token
+}
+
+func TestRequestEmailChange(t *testing.T) {
+	user, _ := entity.NewUser("test", "test", "test@test.com", "password")
+	user.ID = 1
+
+	tests := []struct {
+		name                string
+		in                  *RequestEmailChangeInput
+		findMockReturn      []interface{}
+		findEmailMockReturn []interface{}
+		reservedMockReturn  []interface{}
+		createMockReturn    []interface{}
+		sendMockReturn      error
+		wantErrType         customerrors.CustomErrorType
+		wantErr             bool
+	}{
+		{
+			name:                "success",
+			in:                  &RequestEmailChangeInput{UserID: "1", NewEmail: "new@test.com", Password: "password"},
+			findMockReturn:      []interface{}{user, nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			createMockReturn:    []interface{}{&entity.EmailChange{}, nil},
+			sendMockReturn:      nil,
+			wantErr:             false,
+		},
+		{
+			name:           "error when user not found",
+			in:             &RequestEmailChangeInput{UserID: "1", NewEmail: "new@test.com", Password: "password"},
+			findMockReturn: []interface{}{nil, nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when password is wrong",
+			in:             &RequestEmailChangeInput{UserID: "1", NewEmail: "new@test.com", Password: "wrong"},
+			findMockReturn: []interface{}{user, nil},
+			wantErrType:    customerrors.InvalidCredentials,
+			wantErr:        true,
+		},
+		{
+			name:           "error when email is invalid",
+			in:             &RequestEmailChangeInput{UserID: "1", NewEmail: "invalid", Password: "password"},
+			findMockReturn: []interface{}{user, nil},
+			wantErrType:    customerrors.BadRequest,
+			wantErr:        true,
+		},
+		{
+			name:                "error when email is taken",
+			in:                  &RequestEmailChangeInput{UserID: "1", NewEmail: "taken@test.com", Password: "password"},
+			findMockReturn:      []interface{}{user, nil},
+			findEmailMockReturn: []interface{}{&entity.User{Model: gorm.Model{ID: 2}}, nil},
+			wantErrType:         customerrors.Conflict,
+			wantErr:             true,
+		},
+		{
+			name:                "error when email is held for another user's revert",
+			in:                  &RequestEmailChangeInput{UserID: "1", NewEmail: "held@test.com", Password: "password"},
+			findMockReturn:      []interface{}{user, nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			reservedMockReturn:  []interface{}{&entity.EmailChange{UserID: 2, OldEmail: "held@test.com"}, nil},
+			wantErrType:         customerrors.Conflict,
+			wantErr:             true,
+		},
+		{
+			name:                "success when email is held for own revert",
+			in:                  &RequestEmailChangeInput{UserID: "1", NewEmail: "held@test.com", Password: "password"},
+			findMockReturn:      []interface{}{user, nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			reservedMockReturn:  []interface{}{&entity.EmailChange{UserID: 1, OldEmail: "held@test.com"}, nil},
+			createMockReturn:    []interface{}{&entity.EmailChange{}, nil},
+			wantErr:             false,
+		},
+		{
+			name:                "error when sending email",
+			in:                  &RequestEmailChangeInput{UserID: "1", NewEmail: "new@test.com", Password: "password"},
+			findMockReturn:      []interface{}{user, nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			createMockReturn:    []interface{}{&entity.EmailChange{}, nil},
+			sendMockReturn:      errors.New("error"),
+			wantErrType:         customerrors.InternalServerError,
+			wantErr:             true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserRepo mockUserRepo
+			var mockChangeRepo mockEmailChangeRepo
+			var mockMailer mockMailer
+			mockUserRepo.On("FindByID", test.in.UserID).Return(test.findMockReturn...)
+			if test.findEmailMockReturn != nil {
+				mockUserRepo.On("FindByEmail", test.in.NewEmail).Return(test.findEmailMockReturn...)
+				if test.reservedMockReturn == nil {
+					test.reservedMockReturn = []interface{}{nil, nil}
+				}
+				mockChangeRepo.On("FindRevertableByOldEmail", test.in.NewEmail, mock.Anything).Return(test.reservedMockReturn...)
+			}
+			if test.createMockReturn != nil {
+				mockChangeRepo.On("Create", mock.Anything).Return(test.createMockReturn...)
+				mockMailer.On("Send", test.in.NewEmail, mock.Anything, mock.Anything).Return(test.sendMockReturn)
+			}
+
+			u := NewEmailChangeUseCase(&mockUserRepo, &mockChangeRepo, &mockMailer, "http://localhost:3000")
+			err := u.RequestEmailChange(test.in)
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+			} else {
+				assert.Nil(t, err)
+				change := mockChangeRepo.Calls[len(mockChangeRepo.Calls)-1].Arguments.Get(0).(*entity.EmailChange)
+				body := mockMailer.Calls[0].Arguments.String(2)
+				assert.Contains(t, body, "http://localhost:3000/email/confirm?token=")
+				assert.Contains(t, body, "within 24 hours")
+				assert.Equal(t, change.ConfirmTokenHash, entity.HashToken(tokenFromBody(body)))
+				assert.Equal(t, "test@test.com", user.Email)
+				mockUserRepo.AssertExpectations(t)
+				mockChangeRepo.AssertExpectations(t)
+				mockMailer.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	newChange := func(expiresAt time.Time) *entity.EmailChange {
+		return &entity.EmailChange{
+			UserID:           1,
+			OldEmail:         "old@test.com",
+			NewEmail:         "new@test.com",
+			ConfirmExpiresAt: expiresAt,
+		}
+	}
+
+	tests := []struct {
+		name                string
+		findChangeReturn    []interface{}
+		findEmailMockReturn []interface{}
+		reservedMockReturn  []interface{}
+		updateMockReturn    error
+		wantErrType         customerrors.CustomErrorType
+		wantErr             bool
+	}{
+		{
+			name:                "success",
+			findChangeReturn:    []interface{}{newChange(time.Now().Add(time.Hour)), nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			updateMockReturn:    nil,
+			wantErr:             false,
+		},
+		{
+			name:             "error when token is unknown",
+			findChangeReturn: []interface{}{nil, nil},
+			wantErrType:      customerrors.NotFound,
+			wantErr:          true,
+		},
+		{
+			name:                "error when expired",
+			findChangeReturn:    []interface{}{newChange(time.Now().Add(-time.Hour)), nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			wantErrType:         customerrors.BadRequest,
+			wantErr:             true,
+		},
+		{
+			name:                "error when email was taken meanwhile",
+			findChangeReturn:    []interface{}{newChange(time.Now().Add(time.Hour)), nil},
+			findEmailMockReturn: []interface{}{&entity.User{Model: gorm.Model{ID: 2}}, nil},
+			wantErrType:         customerrors.Conflict,
+			wantErr:             true,
+		},
+		{
+			name:                "error when email is held for another user's revert",
+			findChangeReturn:    []interface{}{newChange(time.Now().Add(time.Hour)), nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			reservedMockReturn:  []interface{}{&entity.EmailChange{UserID: 2, OldEmail: "new@test.com"}, nil},
+			wantErrType:         customerrors.Conflict,
+			wantErr:             true,
+		},
+		{
+			name:                "error when unique violation on update",
+			findChangeReturn:    []interface{}{newChange(time.Now().Add(time.Hour)), nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			updateMockReturn:    fmt.Errorf("failed to confirm email change: %w", entity.ErrAlreadyExists),
+			wantErrType:         customerrors.Conflict,
+			wantErr:             true,
+		},
+		{
+			name:                "error when confirmed concurrently",
+			findChangeReturn:    []interface{}{newChange(time.Now().Add(time.Hour)), nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			updateMockReturn:    fmt.Errorf("failed to confirm email change: %w", entity.ErrStaleUpdate),
+			wantErrType:         customerrors.Conflict,
+			wantErr:             true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserRepo mockUserRepo
+			var mockChangeRepo mockEmailChangeRepo
+			var mockMailer mockMailer
+			user := &entity.User{Model: gorm.Model{ID: 1}, Name: "test", Email: "old@test.com"}
+			mockChangeRepo.On("FindByConfirmTokenHash", entity.HashToken("token")).Return(test.findChangeReturn...)
+			mockUserRepo.On("FindByID", "1").Return(user, nil)
+			mockUserRepo.On("FindByEmail", "new@test.com").Return(test.findEmailMockReturn...)
+			if test.reservedMockReturn == nil {
+				test.reservedMockReturn = []interface{}{nil, nil}
+			}
+			mockChangeRepo.On("FindRevertableByOldEmail", "new@test.com", mock.Anything).Return(test.reservedMockReturn...)
+			mockChangeRepo.On("ConfirmWithUser", mock.Anything, user).Return(test.updateMockReturn)
+			mockMailer.On("Send", "old@test.com", mock.Anything, mock.Anything).Return(nil)
+
+			u := NewEmailChangeUseCase(&mockUserRepo, &mockChangeRepo, &mockMailer, "http://localhost:3000")
+			userResponse, err := u.ConfirmEmailChange("token")
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, user.ID, userResponse.ID)
+				assert.Equal(t, "new@test.com", user.Email)
+				change := test.findChangeReturn[0].(*entity.EmailChange)
+				body := mockMailer.Calls[0].Arguments.String(2)
+				assert.Contains(t, body, "http://localhost:3000/email/revert?token=")
+				assert.Contains(t, body, "within 7 days")
+				assert.Equal(t, *change.RevertTokenHash, entity.HashToken(tokenFromBody(body)))
+				mockChangeRepo.AssertExpectations(t)
+				mockMailer.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestRevertEmailChange(t *testing.T) {
+	newChange := func(revertExpiresAt time.Time) *entity.EmailChange {
+		confirmedAt := revertExpiresAt.Add(-entity.EmailChangeRevertTTL)
+		return &entity.EmailChange{
+			UserID:          1,
+			OldEmail:        "old@test.com",
+			NewEmail:        "new@test.com",
+			ConfirmedAt:     &confirmedAt,
+			RevertExpiresAt: &revertExpiresAt,
+		}
+	}
+
+	tests := []struct {
+		name                string
+		findChangeReturn    []interface{}
+		findEmailMockReturn []interface{}
+		updateMockReturn    error
+		wantErrType         customerrors.CustomErrorType
+		wantErr             bool
+	}{
+		{
+			name:                "success",
+			findChangeReturn:    []interface{}{newChange(time.Now().Add(time.Hour)), nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			updateMockReturn:    nil,
+			wantErr:             false,
+		},
+		{
+			name:             "error when token is unknown",
+			findChangeReturn: []interface{}{nil, nil},
+			wantErrType:      customerrors.NotFound,
+			wantErr:          true,
+		},
+		{
+			name:                "error when expired",
+			findChangeReturn:    []interface{}{newChange(time.Now().Add(-time.Hour)), nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			wantErrType:         customerrors.BadRequest,
+			wantErr:             true,
+		},
+		{
+			name:                "error when old email was taken meanwhile",
+			findChangeReturn:    []interface{}{newChange(time.Now().Add(time.Hour)), nil},
+			findEmailMockReturn: []interface{}{&entity.User{Model: gorm.Model{ID: 2}}, nil},
+			wantErrType:         customerrors.Conflict,
+			wantErr:             true,
+		},
+		{
+			name:                "error when reverted concurrently",
+			findChangeReturn:    []interface{}{newChange(time.Now().Add(time.Hour)), nil},
+			findEmailMockReturn: []interface{}{nil, nil},
+			updateMockReturn:    fmt.Errorf("failed to revert email change: %w", entity.ErrStaleUpdate),
+			wantErrType:         customerrors.Conflict,
+			wantErr:             true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserRepo mockUserRepo
+			var mockChangeRepo mockEmailChangeRepo
+			user := &entity.User{Model: gorm.Model{ID: 1}, Name: "test", Email: "new@test.com"}
+			mockChangeRepo.On("FindByRevertTokenHash", entity.HashToken("token")).Return(test.findChangeReturn...)
+			mockUserRepo.On("FindByID", "1").Return(user, nil)
+			mockUserRepo.On("FindByEmail", "old@test.com").Return(test.findEmailMockReturn...)
+			// The change being reverted holds the old address for this same user
+			mockChangeRepo.On("FindRevertableByOldEmail", "old@test.com", mock.Anything).Return(test.findChangeReturn...)
+			mockChangeRepo.On("RevertWithUser", mock.Anything, user).Return(test.updateMockReturn)
+
+			u := NewEmailChangeUseCase(&mockUserRepo, &mockChangeRepo, &mockMailer{}, "http://localhost:3000")
+			userResponse, err := u.RevertEmailChange("token")
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, user.ID, userResponse.ID)
+				assert.Equal(t, "old@test.com", user.Email)
+				mockChangeRepo.AssertExpectations(t)
+			}
+		})
+	}
+}
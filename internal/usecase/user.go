@@ -1,42 +1,68 @@
 package usecase
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"chatapp/internal/domain/entity"
 	"chatapp/pkg/errors"
+	"chatapp/pkg/patch"
+)
+
+const (
+	// DefaultUsersPageSize is the number of users listed when no limit is given
+	DefaultUsersPageSize = 20
+	// MaxUsersPageSize caps the number of users listed in one page
+	MaxUsersPageSize = 100
 )
 
 // UserRepository is a repository for the user entity
 type UserRepository interface {
 	Create(user *entity.User) (*entity.User, error)
 	FindByEmail(email string) (*entity.User, error)
+	FindByHandle(handle string) (*entity.User, error)
 	FindByID(id string) (*entity.User, error)
-	FindAll() ([]*entity.User, error)
+	FindPage(query *entity.UserListQuery) ([]*entity.User, error)
 	Update(user *entity.User) error
 	Delete(user *entity.User) error
+	FindDeletedByEmail(email string) (*entity.User, error)
+	Restore(user *entity.User) error
+	PurgeDeletedBefore(cutoff time.Time) (int64, error)
 }
 
 // UserUseCase is a use case for the user entity
 type UserUseCase struct {
-	UserRepo UserRepository
+	UserRepo        UserRepository
+	EmailChangeRepo EmailChangeRepository
 }
 
-// UserResponse is a response for the user entity
+// UserResponse is the public profile of a user, it never includes the email
 type UserResponse struct {
-	ID   uint
-	Name string
+	ID             uint
+	Name           string
+	Handle         string
+	DisplayName    string
+	Bio            string
+	Timezone       string
+	Locale         string
+	AvatarInitials string
 }
 
-// UsersResponse is a response for the user entity
+// UsersResponse is a response for a page of users
 type UsersResponse struct {
-	Users []UserResponse
+	Users      []UserResponse
+	NextCursor string
 }
 
 // CreateUserInput is an input for creating a user
 type CreateUserInput struct {
 	Name     string
+	Handle   string
 	Email    string
 	Password string
 }
@@ -47,6 +73,15 @@ type AuthenticateUserInput struct {
 	Password string
 }
 
+// ListUsersInput is an input for listing users
+type ListUsersInput struct {
+	Query  string
+	Cursor string
+	Limit  int
+	Sort   string
+	Order  string
+}
+
 // UpdateUserInput is an input for updating a user
 type UpdateUserInput struct {
 	UserID string
@@ -54,15 +89,33 @@ type UpdateUserInput struct {
 	Email  string
 }
 
+// PatchUserInput is an input for partially updating a user, unset fields are left as they are
+type PatchUserInput struct {
+	UserID string
+	Name   patch.Field[string]
+	Email  patch.Field[string]
+}
+
+// UpdateProfileInput is an input for updating the profile of a user
+type UpdateProfileInput struct {
+	UserID      string
+	Handle      *string
+	DisplayName *string
+	Bio         *string
+	Timezone    *string
+	Locale      *string
+}
+
 // NewUserUseCase creates a new user use case
-func NewUserUseCase(repo UserRepository) *UserUseCase {
-	return &UserUseCase{UserRepo: repo}
+func NewUserUseCase(repo UserRepository, emailChangeRepo EmailChangeRepository) *UserUseCase {
+	return &UserUseCase{UserRepo: repo, EmailChangeRepo: emailChangeRepo}
 }
 
 // NewCreateUserInput creates a new input for creating a user
-func NewCreateUserInput(name, email, password string) *CreateUserInput {
+func NewCreateUserInput(name, handle, email, password string) *CreateUserInput {
 	return &CreateUserInput{
 		Name:     name,
+		Handle:   handle,
 		Email:    email,
 		Password: password,
 	}
@@ -76,6 +129,17 @@ func NewAuthenticateUserInput(email, password string) *AuthenticateUserInput {
 	}
 }
 
+// NewListUsersInput creates a new input for listing users
+func NewListUsersInput(query, cursor string, limit int, sort, order string) *ListUsersInput {
+	return &ListUsersInput{
+		Query:  query,
+		Cursor: cursor,
+		Limit:  limit,
+		Sort:   sort,
+		Order:  order,
+	}
+}
+
 func NewUpdateUserInput(userID, name, email string) *UpdateUserInput {
 	return &UpdateUserInput{
 		UserID: userID,
@@ -84,26 +148,61 @@ func NewUpdateUserInput(userID, name, email string) *UpdateUserInput {
 	}
 }
 
+// NewPatchUserInput creates a new input for partially updating a user
+func NewPatchUserInput(userID string, name, email patch.Field[string]) *PatchUserInput {
+	return &PatchUserInput{
+		UserID: userID,
+		Name:   name,
+		Email:  email,
+	}
+}
+
+// NewUpdateProfileInput creates a new input for updating the profile of a user
+func NewUpdateProfileInput(userID string, handle, displayName, bio, timezone, locale *string) *UpdateProfileInput {
+	return &UpdateProfileInput{
+		UserID:      userID,
+		Handle:      handle,
+		DisplayName: displayName,
+		Bio:         bio,
+		Timezone:    timezone,
+		Locale:      locale,
+	}
+}
+
+// newUserResponse creates the public response for a user
+func newUserResponse(user *entity.User) *UserResponse {
+	return &UserResponse{
+		ID:             user.ID,
+		Name:           user.Name,
+		Handle:         user.Handle,
+		DisplayName:    user.DisplayName,
+		Bio:            user.Bio,
+		Timezone:       user.Timezone,
+		Locale:         user.Locale,
+		AvatarInitials: user.Initials(),
+	}
+}
+
 // Create creates a new user
 func (u *UserUseCase) CreateUser(input *CreateUserInput) (*UserResponse, *errors.CustomError) {
 	log.Println("CreateUser:", input)
 
-	user, err := entity.NewUser(input.Name, input.Email, input.Password)
+	user, err := entity.NewUser(input.Name, input.Handle, input.Email, input.Password)
 	if err != nil {
 		return nil, errors.NewCustomError(errors.BadRequest, err)
 	}
 
-	newUser, err := u.UserRepo.Create(user)
-	if err != nil {
-		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	// A user who changed away from the email can still revert to it
+	if customErr := checkEmailNotReserved(u.EmailChangeRepo, user.Email, 0); customErr != nil {
+		return nil, customErr
 	}
 
-	responseUser := &UserResponse{
-		ID:   newUser.ID,
-		Name: newUser.Name,
+	newUser, err := u.UserRepo.Create(user)
+	if err != nil {
+		return nil, repositoryError(err)
 	}
 
-	return responseUser, nil
+	return newUserResponse(newUser), nil
 }
 
 // AuthenticateUser authenticates a user
@@ -123,12 +222,7 @@ func (u *UserUseCase) AuthenticateUser(input *AuthenticateUserInput) (*UserRespo
 		return nil, errors.NewCustomError(errors.InvalidCredentials, fmt.Errorf("invalid credentials"))
 	}
 
-	responseUser := &UserResponse{
-		ID:   user.ID,
-		Name: user.Name,
-	}
-
-	return responseUser, nil
+	return newUserResponse(user), nil
 }
 
 // ReadUser reads a user
@@ -144,53 +238,141 @@ func (u *UserUseCase) ReadUser(userID string) (*UserResponse, *errors.CustomErro
 		return nil, errors.NewCustomError(errors.NotFound, err)
 	}
 
-	responseUser := &UserResponse{
-		ID:   user.ID,
-		Name: user.Name,
-	}
-
-	return responseUser, nil
+	return newUserResponse(user), nil
 }
 
-// FindAllUsers finds all users
-func (u *UserUseCase) ReadAllUsers() (*UsersResponse, *errors.CustomError) {
-	log.Println("ReadAllUsers")
+// ReadUsers reads one page of users
+func (u *UserUseCase) ReadUsers(input *ListUsersInput) (*UsersResponse, *errors.CustomError) {
+	log.Println("ReadUsers:", input)
 
-	users, err := u.UserRepo.FindAll()
+	query, err := newUserListQuery(input)
+	if err != nil {
+		return nil, errors.NewCustomError(errors.BadRequest, err)
+	}
+
+	// Fetch one extra user to know whether there is a next page
+	pageSize := query.Limit
+	query.Limit++
+	users, err := u.UserRepo.FindPage(query)
 	if err != nil {
 		return nil, errors.NewCustomError(errors.InternalServerError, err)
 	}
 
+	var nextCursor string
+	if len(users) > pageSize {
+		users = users[:pageSize]
+		last := users[len(users)-1]
+		nextCursor = encodeUserCursor(&entity.UserCursor{
+			Value: query.SortBy.CursorValue(last),
+			ID:    last.ID,
+		})
+	}
+
 	responseUsers := make([]UserResponse, len(users))
 	for i, user := range users {
-		responseUsers[i] = UserResponse{
-			ID:   user.ID,
-			Name: user.Name,
-		}
+		responseUsers[i] = *newUserResponse(user)
 	}
 
-	return &UsersResponse{Users: responseUsers}, nil
+	return &UsersResponse{Users: responseUsers, NextCursor: nextCursor}, nil
 }
 
-// UpdateUser updates a user
-func (u *UserUseCase) UpdateUser(input *UpdateUserInput) *errors.CustomError {
+// UpdateUser replaces the name of a user and returns the updated user, the email must be unchanged
+func (u *UserUseCase) UpdateUser(input *UpdateUserInput) (*UserResponse, *errors.CustomError) {
 	log.Println("UpdateUser:", input)
 
+	return u.patchUser(&PatchUserInput{
+		UserID: input.UserID,
+		Name:   patch.NewField(input.Name),
+		Email:  patch.NewField(input.Email),
+	})
+}
+
+// PatchUser updates the fields set in the input and returns the updated user
+func (u *UserUseCase) PatchUser(input *PatchUserInput) (*UserResponse, *errors.CustomError) {
+	log.Println("PatchUser:", input.UserID)
+
+	return u.patchUser(input)
+}
+
+func (u *UserUseCase) patchUser(input *PatchUserInput) (*UserResponse, *errors.CustomError) {
 	user, err := u.UserRepo.FindByID(input.UserID)
 	if err != nil {
-		return errors.NewCustomError(errors.InternalServerError, err)
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
 	}
 	if user == nil {
-		return errors.NewCustomError(errors.NotFound, fmt.Errorf("user not found"))
+		return nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("user not found"))
 	}
 
-	user.Name = input.Name
-	user.Email = input.Email
+	invalidFields := make(map[string]string)
+	validateField(invalidFields, "name", input.Name, entity.ValidateName)
+	validateField(invalidFields, "email", input.Email, entity.ValidateEmail)
+	// The email is only accepted unchanged, changing it has to be confirmed from the new address
+	if _, ok := invalidFields["email"]; !ok && input.Email.Set && input.Email.Value != user.Email {
+		invalidFields["email"] = "email changes must be confirmed, request one with POST /api/v1/users/:id/email"
+	}
+	if len(invalidFields) > 0 {
+		return nil, errors.NewValidationError(invalidFields)
+	}
+
+	if input.Name.Set {
+		user.Name = input.Name.Value
+	}
 	if err := u.UserRepo.Update(user); err != nil {
-		return errors.NewCustomError(errors.InternalServerError, err)
+		return nil, repositoryError(err)
 	}
 
-	return nil
+	return newUserResponse(user), nil
+}
+
+// UpdateProfile updates the profile fields given in the input and returns the updated user
+func (u *UserUseCase) UpdateProfile(input *UpdateProfileInput) (*UserResponse, *errors.CustomError) {
+	log.Println("UpdateProfile:", input.UserID)
+
+	user, err := u.UserRepo.FindByID(input.UserID)
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if user == nil {
+		return nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("user not found"))
+	}
+
+	// Fields are checked before the handle is looked up, and reported the way PatchUser does
+	invalidFields := make(map[string]string)
+	validateProfileField(invalidFields, "handle", input.Handle, entity.ValidateHandle)
+	validateProfileField(invalidFields, "display_name", input.DisplayName, entity.ValidateDisplayName)
+	validateProfileField(invalidFields, "bio", input.Bio, entity.ValidateBio)
+	validateProfileField(invalidFields, "timezone", input.Timezone, entity.ValidateTimezone)
+	validateProfileField(invalidFields, "locale", input.Locale, entity.ValidateLocale)
+	if len(invalidFields) > 0 {
+		return nil, errors.NewValidationError(invalidFields)
+	}
+
+	if input.Handle != nil && *input.Handle != user.Handle {
+		other, err := u.UserRepo.FindByHandle(*input.Handle)
+		if err != nil {
+			return nil, errors.NewCustomError(errors.InternalServerError, err)
+		}
+		if other != nil {
+			return nil, errors.NewCustomError(errors.Conflict, fmt.Errorf("handle already taken"))
+		}
+	}
+
+	update := entity.ProfileUpdate{
+		Handle:      input.Handle,
+		DisplayName: input.DisplayName,
+		Bio:         input.Bio,
+		Timezone:    input.Timezone,
+		Locale:      input.Locale,
+	}
+	if err := user.UpdateProfile(update); err != nil {
+		return nil, errors.NewCustomError(errors.BadRequest, err)
+	}
+
+	if err := u.UserRepo.Update(user); err != nil {
+		return nil, repositoryError(err)
+	}
+
+	return newUserResponse(user), nil
 }
 
 // DestroyUser deletes a user
@@ -209,3 +391,140 @@ func (u *UserUseCase) DestroyUser(userID string) *errors.CustomError {
 
 	return nil
 }
+
+// RestoreUser restores a deleted user within the retention period, authenticated by its credentials
+func (u *UserUseCase) RestoreUser(input *AuthenticateUserInput) (*UserResponse, *errors.CustomError) {
+	log.Println("RestoreUser:", input.Email)
+
+	user, err := u.UserRepo.FindDeletedByEmail(input.Email)
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if user == nil || !user.IsRestorable(time.Now()) {
+		return nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("deleted user not found"))
+	}
+
+	if !user.CheckPassword(input.Password) {
+		return nil, errors.NewCustomError(errors.InvalidCredentials, fmt.Errorf("invalid credentials"))
+	}
+
+	// Deleted users keep their email and handle, a conflict only comes from a concurrent restore
+	if err := u.UserRepo.Restore(user); err != nil {
+		return nil, repositoryError(err)
+	}
+
+	return newUserResponse(user), nil
+}
+
+// PurgeDeletedUsers permanently deletes the users deleted longer ago than the retention period
+func (u *UserUseCase) PurgeDeletedUsers() (int64, *errors.CustomError) {
+	cutoff := time.Now().Add(-entity.DeletedAccountRetention)
+	purged, err := u.UserRepo.PurgeDeletedBefore(cutoff)
+	if err != nil {
+		return 0, errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	log.Println("PurgeDeletedUsers:", purged)
+	return purged, nil
+}
+
+// repositoryError maps a repository error to a conflict when a unique field is taken
+func repositoryError(err error) *errors.CustomError {
+	if stderrors.Is(err, entity.ErrAlreadyExists) || stderrors.Is(err, entity.ErrStaleUpdate) {
+		return errors.NewCustomError(errors.Conflict, err)
+	}
+	return errors.NewCustomError(errors.InternalServerError, err)
+}
+
+// newUserListQuery validates a list input and converts it to a repository query
+func newUserListQuery(input *ListUsersInput) (*entity.UserListQuery, error) {
+	query := &entity.UserListQuery{
+		Prefix: strings.TrimSpace(input.Query),
+		SortBy: entity.UserSortByID,
+		Limit:  input.Limit,
+	}
+
+	if input.Sort != "" {
+		query.SortBy = entity.UserSortField(input.Sort)
+		if !query.SortBy.IsValid() {
+			return nil, fmt.Errorf("invalid sort field: %s", input.Sort)
+		}
+	}
+
+	switch input.Order {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return nil, fmt.Errorf("invalid sort order: %s", input.Order)
+	}
+
+	switch {
+	case query.Limit < 0:
+		return nil, fmt.Errorf("limit must not be negative")
+	case query.Limit == 0:
+		query.Limit = DefaultUsersPageSize
+	case query.Limit > MaxUsersPageSize:
+		query.Limit = MaxUsersPageSize
+	}
+
+	if input.Cursor != "" {
+		cursor, err := decodeUserCursor(input.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if query.SortBy == entity.UserSortByCreatedAt {
+			if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+				return nil, fmt.Errorf("invalid cursor: %w", err)
+			}
+		}
+		query.After = cursor
+	}
+
+	return query, nil
+}
+
+// encodeUserCursor encodes a cursor into an opaque string for clients
+func encodeUserCursor(cursor *entity.UserCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeUserCursor decodes a cursor given by a client
+func decodeUserCursor(s string) (*entity.UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	var cursor entity.UserCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	return &cursor, nil
+}
+
+// validateProfileField records why a given profile field is invalid, nil fields are left out
+func validateProfileField(invalidFields map[string]string, name string, value *string, validate func(string) error) {
+	if value == nil {
+		return
+	}
+	if err := validate(*value); err != nil {
+		invalidFields[name] = err.Error()
+	}
+}
+
+// validateField records why a set patch field is invalid, required fields cannot be null
+func validateField(invalidFields map[string]string, name string, field patch.Field[string], validate func(string) error) {
+	if !field.Set {
+		return
+	}
+	if field.Null {
+		invalidFields[name] = fmt.Sprintf("%s must not be null", name)
+		return
+	}
+	if err := validate(field.Value); err != nil {
+		invalidFields[name] = err.Error()
+	}
+}
@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	stderrors "errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"chatapp/internal/domain/entity"
+	"chatapp/pkg/errors"
+)
+
+// UserRelationRepository is a repository for the user relation entity
+type UserRelationRepository interface {
+	Create(relation *entity.UserRelation) (*entity.UserRelation, error)
+	Delete(userID, targetID uint, kind entity.UserRelationKind) error
+	FindTargets(userID uint, kind entity.UserRelationKind) ([]*entity.User, error)
+	FindByUserID(userID uint) ([]*entity.UserRelation, error)
+	Exists(userID, targetID uint, kind entity.UserRelationKind) (bool, error)
+	ExistsBetween(userID, otherID uint, kind entity.UserRelationKind) (bool, error)
+}
+
+// UserRelationUseCase is a use case for the blocks and mutes users set on each other.
+// Use cases delivering messages, notifications or presence check IsBlockedBetween
+// and IsMuted so the lists are enforced whatever the client is.
+type UserRelationUseCase struct {
+	UserRepo     UserRepository
+	RelationRepo UserRelationRepository
+}
+
+// NewUserRelationUseCase creates a new user relation use case
+func NewUserRelationUseCase(userRepo UserRepository, relationRepo UserRelationRepository) *UserRelationUseCase {
+	return &UserRelationUseCase{
+		UserRepo:     userRepo,
+		RelationRepo: relationRepo,
+	}
+}
+
+// BlockUser blocks the target for the user, blocking twice is not an error
+func (u *UserRelationUseCase) BlockUser(userID, targetID string) *errors.CustomError {
+	log.Println("BlockUser:", userID, targetID)
+	return u.addRelation(userID, targetID, entity.UserRelationBlock)
+}
+
+// UnblockUser removes the block of the user on the target
+func (u *UserRelationUseCase) UnblockUser(userID, targetID string) *errors.CustomError {
+	log.Println("UnblockUser:", userID, targetID)
+	return u.removeRelation(userID, targetID, entity.UserRelationBlock)
+}
+
+// ReadBlockedUsers lists the users blocked by the user
+func (u *UserRelationUseCase) ReadBlockedUsers(userID string) ([]UserResponse, *errors.CustomError) {
+	log.Println("ReadBlockedUsers:", userID)
+	return u.readTargets(userID, entity.UserRelationBlock)
+}
+
+// MuteUser mutes the target for the user, muting twice is not an error
+func (u *UserRelationUseCase) MuteUser(userID, targetID string) *errors.CustomError {
+	log.Println("MuteUser:", userID, targetID)
+	return u.addRelation(userID, targetID, entity.UserRelationMute)
+}
+
+// UnmuteUser removes the mute of the user on the target
+func (u *UserRelationUseCase) UnmuteUser(userID, targetID string) *errors.CustomError {
+	log.Println("UnmuteUser:", userID, targetID)
+	return u.removeRelation(userID, targetID, entity.UserRelationMute)
+}
+
+// ReadMutedUsers lists the users muted by the user
+func (u *UserRelationUseCase) ReadMutedUsers(userID string) ([]UserResponse, *errors.CustomError) {
+	log.Println("ReadMutedUsers:", userID)
+	return u.readTargets(userID, entity.UserRelationMute)
+}
+
+// IsBlockedBetween reports whether either user blocked the other.
+// A block cuts both ways: neither can DM, mention-notify or see the presence of the other.
+func (u *UserRelationUseCase) IsBlockedBetween(userID, otherID uint) (bool, *errors.CustomError) {
+	blocked, err := u.RelationRepo.ExistsBetween(userID, otherID, entity.UserRelationBlock)
+	if err != nil {
+		return false, errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	return blocked, nil
+}
+
+// IsMuted reports whether the user muted the target, their messages are collapsed and do not notify the user
+func (u *UserRelationUseCase) IsMuted(userID, targetID uint) (bool, *errors.CustomError) {
+	muted, err := u.RelationRepo.Exists(userID, targetID, entity.UserRelationMute)
+	if err != nil {
+		return false, errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	return muted, nil
+}
+
+func (u *UserRelationUseCase) addRelation(userID, targetID string, kind entity.UserRelationKind) *errors.CustomError {
+	user, target, customErr := u.findUsers(userID, targetID)
+	if customErr != nil {
+		return customErr
+	}
+
+	relation, err := entity.NewUserRelation(user.ID, target.ID, kind)
+	if err != nil {
+		return errors.NewCustomError(errors.BadRequest, err)
+	}
+
+	if _, err := u.RelationRepo.Create(relation); err != nil && !stderrors.Is(err, entity.ErrAlreadyExists) {
+		return errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	return nil
+}
+
+// removeRelation deletes the relation by the target ID without looking the target up,
+// so that a block or mute on a deleted user can still be removed before the purge does it
+func (u *UserRelationUseCase) removeRelation(userID, targetID string, kind entity.UserRelationKind) *errors.CustomError {
+	user, err := u.UserRepo.FindByID(userID)
+	if err != nil {
+		return errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if user == nil {
+		return errors.NewCustomError(errors.NotFound, fmt.Errorf("user not found"))
+	}
+
+	target, err := strconv.ParseUint(targetID, 10, 0)
+	if err != nil || target == 0 {
+		return errors.NewCustomError(errors.NotFound, fmt.Errorf("target user not found"))
+	}
+
+	if err := u.RelationRepo.Delete(user.ID, uint(target), kind); err != nil {
+		return errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	return nil
+}
+
+func (u *UserRelationUseCase) readTargets(userID string, kind entity.UserRelationKind) ([]UserResponse, *errors.CustomError) {
+	user, err := u.UserRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if user == nil {
+		return nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("user not found"))
+	}
+
+	targets, err := u.RelationRepo.FindTargets(user.ID, kind)
+	if err != nil {
+		return nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+
+	users := make([]UserResponse, 0, len(targets))
+	for _, target := range targets {
+		users = append(users, *newUserResponse(target))
+	}
+
+	return users, nil
+}
+
+func (u *UserRelationUseCase) findUsers(userID, targetID string) (*entity.User, *entity.User, *errors.CustomError) {
+	user, err := u.UserRepo.FindByID(userID)
+	if err != nil {
+		return nil, nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if user == nil {
+		return nil, nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("user not found"))
+	}
+
+	target, err := u.UserRepo.FindByID(targetID)
+	if err != nil {
+		return nil, nil, errors.NewCustomError(errors.InternalServerError, err)
+	}
+	if target == nil {
+		return nil, nil, errors.NewCustomError(errors.NotFound, fmt.Errorf("target user not found"))
+	}
+
+	return user, target, nil
+}
@@ -0,0 +1,297 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"chatapp/internal/domain/entity"
+	customerrors "chatapp/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type mockUserRelationRepo struct {
+	mock.Mock
+}
+
+func (m *mockUserRelationRepo) Create(relation *entity.UserRelation) (*entity.UserRelation, error) {
+	args := m.Called(relation)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.UserRelation), args.Error(1)
+}
+
+func (m *mockUserRelationRepo) Delete(userID, targetID uint, kind entity.UserRelationKind) error {
+	args := m.Called(userID, targetID, kind)
+	return args.Error(0)
+}
+
+func (m *mockUserRelationRepo) FindTargets(userID uint, kind entity.UserRelationKind) ([]*entity.User, error) {
+	args := m.Called(userID, kind)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.User), args.Error(1)
+}
+
+func (m *mockUserRelationRepo) FindByUserID(userID uint) ([]*entity.UserRelation, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.UserRelation), args.Error(1)
+}
+
+func (m *mockUserRelationRepo) Exists(userID, targetID uint, kind entity.UserRelationKind) (bool, error) {
+	args := m.Called(userID, targetID, kind)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockUserRelationRepo) ExistsBetween(userID, otherID uint, kind entity.UserRelationKind) (bool, error) {
+	args := m.Called(userID, otherID, kind)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestBlockUser(t *testing.T) {
+	user := &entity.User{Model: gorm.Model{ID: 1}, Name: "test"}
+	target := &entity.User{Model: gorm.Model{ID: 2}, Name: "target"}
+
+	tests := []struct {
+		name             string
+		targetID         string
+		findTargetReturn []interface{}
+		createMockReturn []interface{}
+		wantErrType      customerrors.CustomErrorType
+		wantErr          bool
+	}{
+		{
+			name:             "success",
+			targetID:         "2",
+			findTargetReturn: []interface{}{target, nil},
+			createMockReturn: []interface{}{&entity.UserRelation{}, nil},
+			wantErr:          false,
+		},
+		{
+			name:             "success when already blocked",
+			targetID:         "2",
+			findTargetReturn: []interface{}{target, nil},
+			createMockReturn: []interface{}{nil, fmt.Errorf("failed to create user relation: %w", entity.ErrAlreadyExists)},
+			wantErr:          false,
+		},
+		{
+			name:             "error when target not found",
+			targetID:         "2",
+			findTargetReturn: []interface{}{nil, nil},
+			wantErrType:      customerrors.NotFound,
+			wantErr:          true,
+		},
+		{
+			name:             "error when blocking yourself",
+			targetID:         "1",
+			findTargetReturn: []interface{}{user, nil},
+			wantErrType:      customerrors.BadRequest,
+			wantErr:          true,
+		},
+		{
+			name:             "error when creating relation",
+			targetID:         "2",
+			findTargetReturn: []interface{}{target, nil},
+			createMockReturn: []interface{}{nil, errors.New("error")},
+			wantErrType:      customerrors.InternalServerError,
+			wantErr:          true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserRepo mockUserRepo
+			var mockRelationRepo mockUserRelationRepo
+			if test.targetID != "1" {
+				mockUserRepo.On("FindByID", "1").Return(user, nil)
+			}
+			mockUserRepo.On("FindByID", test.targetID).Return(test.findTargetReturn...)
+			if test.createMockReturn != nil {
+				mockRelationRepo.On("Create", mock.Anything).Return(test.createMockReturn...)
+			}
+
+			u := NewUserRelationUseCase(&mockUserRepo, &mockRelationRepo)
+			err := u.BlockUser("1", test.targetID)
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+			} else {
+				assert.Nil(t, err)
+				relation := mockRelationRepo.Calls[0].Arguments.Get(0).(*entity.UserRelation)
+				assert.Equal(t, &entity.UserRelation{UserID: 1, TargetID: 2, Kind: entity.UserRelationBlock}, relation)
+			}
+		})
+	}
+}
+
+func TestUnmuteUser(t *testing.T) {
+	user := &entity.User{Model: gorm.Model{ID: 1}, Name: "test"}
+
+	tests := []struct {
+		name             string
+		targetID         string
+		findUserReturn   []interface{}
+		deleteMockReturn error
+		wantErrType      customerrors.CustomErrorType
+		wantErr          bool
+	}{
+		{
+			name:             "success",
+			targetID:         "2",
+			findUserReturn:   []interface{}{user, nil},
+			deleteMockReturn: nil,
+			wantErr:          false,
+		},
+		{
+			name:           "error when user not found",
+			targetID:       "2",
+			findUserReturn: []interface{}{nil, nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when target ID is invalid",
+			targetID:       "abc",
+			findUserReturn: []interface{}{user, nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:             "error when deleting relation",
+			targetID:         "2",
+			findUserReturn:   []interface{}{user, nil},
+			deleteMockReturn: errors.New("error"),
+			wantErrType:      customerrors.InternalServerError,
+			wantErr:          true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserRepo mockUserRepo
+			var mockRelationRepo mockUserRelationRepo
+			mockUserRepo.On("FindByID", "1").Return(test.findUserReturn...)
+			mockRelationRepo.On("Delete", uint(1), uint(2), entity.UserRelationMute).Return(test.deleteMockReturn)
+
+			u := NewUserRelationUseCase(&mockUserRepo, &mockRelationRepo)
+			err := u.UnmuteUser("1", test.targetID)
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+			} else {
+				assert.Nil(t, err)
+				mockRelationRepo.AssertExpectations(t)
+				// The target is deleted by ID, a deleted target must not make it fail
+				mockUserRepo.AssertNotCalled(t, "FindByID", "2")
+			}
+		})
+	}
+}
+
+func TestReadBlockedUsers(t *testing.T) {
+	user := &entity.User{Model: gorm.Model{ID: 1}, Name: "test"}
+
+	tests := []struct {
+		name           string
+		findUserReturn []interface{}
+		findMockReturn []interface{}
+		want           []UserResponse
+		wantErrType    customerrors.CustomErrorType
+		wantErr        bool
+	}{
+		{
+			name:           "success",
+			findUserReturn: []interface{}{user, nil},
+			findMockReturn: []interface{}{[]*entity.User{{Model: gorm.Model{ID: 2}, Name: "target", Handle: "target"}}, nil},
+			want:           []UserResponse{{ID: 2, Name: "target", Handle: "target", AvatarInitials: "T"}},
+			wantErr:        false,
+		},
+		{
+			name:           "success with no blocked users",
+			findUserReturn: []interface{}{user, nil},
+			findMockReturn: []interface{}{[]*entity.User{}, nil},
+			want:           []UserResponse{},
+			wantErr:        false,
+		},
+		{
+			name:           "error when user not found",
+			findUserReturn: []interface{}{nil, nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockUserRepo mockUserRepo
+			var mockRelationRepo mockUserRelationRepo
+			mockUserRepo.On("FindByID", "1").Return(test.findUserReturn...)
+			if test.findMockReturn != nil {
+				mockRelationRepo.On("FindTargets", uint(1), entity.UserRelationBlock).Return(test.findMockReturn...)
+			}
+
+			u := NewUserRelationUseCase(&mockUserRepo, &mockRelationRepo)
+			users, err := u.ReadBlockedUsers("1")
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, test.want, users)
+			}
+		})
+	}
+}
+
+func TestIsBlockedBetween(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockReturn  []interface{}
+		wantBlocked bool
+		wantErr     bool
+	}{
+		{
+			name:        "blocked",
+			mockReturn:  []interface{}{true, nil},
+			wantBlocked: true,
+			wantErr:     false,
+		},
+		{
+			name:        "not blocked",
+			mockReturn:  []interface{}{false, nil},
+			wantBlocked: false,
+			wantErr:     false,
+		},
+		{
+			name:        "error",
+			mockReturn:  []interface{}{false, errors.New("error")},
+			wantBlocked: false,
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockRelationRepo mockUserRelationRepo
+			mockRelationRepo.On("ExistsBetween", uint(1), uint(2), entity.UserRelationBlock).Return(test.mockReturn...)
+
+			u := NewUserRelationUseCase(&mockUserRepo{}, &mockRelationRepo)
+			blocked, err := u.IsBlockedBetween(1, 2)
+			assert.Equal(t, test.wantBlocked, blocked)
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, customerrors.InternalServerError, err.Type)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
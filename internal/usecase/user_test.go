@@ -2,12 +2,18 @@ package usecase
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"chatapp/internal/domain/entity"
+	customerrors "chatapp/pkg/errors"
+	"chatapp/pkg/patch"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 type mockUserRepo struct {
@@ -30,6 +36,14 @@ func (m *mockUserRepo) FindByEmail(email string) (*entity.User, error) {
 	return args.Get(0).(*entity.User), args.Error(1)
 }
 
+func (m *mockUserRepo) FindByHandle(handle string) (*entity.User, error) {
+	args := m.Called(handle)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.User), args.Error(1)
+}
+
 func (m *mockUserRepo) FindByID(id string) (*entity.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -38,8 +52,8 @@ func (m *mockUserRepo) FindByID(id string) (*entity.User, error) {
 	return args.Get(0).(*entity.User), args.Error(1)
 }
 
-func (m *mockUserRepo) FindAll() ([]*entity.User, error) {
-	args := m.Called()
+func (m *mockUserRepo) FindPage(query *entity.UserListQuery) ([]*entity.User, error) {
+	args := m.Called(query)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -56,17 +70,38 @@ func (m *mockUserRepo) Delete(user *entity.User) error {
 	return args.Error(0)
 }
 
+func (m *mockUserRepo) FindDeletedByEmail(email string) (*entity.User, error) {
+	args := m.Called(email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.User), args.Error(1)
+}
+
+func (m *mockUserRepo) Restore(user *entity.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	args := m.Called(cutoff)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func TestCreateUser(t *testing.T) {
 	tests := []struct {
-		name       string
-		in         *CreateUserInput
-		mockReturn []interface{}
-		wantErr    bool
+		name           string
+		in             *CreateUserInput
+		reservedReturn []interface{}
+		mockReturn     []interface{}
+		wantErrType    customerrors.CustomErrorType
+		wantErr        bool
 	}{
 		{
 			name: "success",
 			in: &CreateUserInput{
 				Name:     "test",
+				Handle:   "test",
 				Email:    "test@test.com",
 				Password: "password",
 			},
@@ -84,23 +119,61 @@ func TestCreateUser(t *testing.T) {
 			name: "error when creating user",
 			in: &CreateUserInput{
 				Name:     "test",
+				Handle:   "test",
 				Email:    "test@test.com",
 				Password: "password",
 			},
 			mockReturn: []interface{}{nil, errors.New("error")},
 			wantErr:    true,
 		},
+		{
+			name: "error when email already exists",
+			in: &CreateUserInput{
+				Name:     "test",
+				Handle:   "test",
+				Email:    "test@test.com",
+				Password: "password",
+			},
+			mockReturn:  []interface{}{nil, fmt.Errorf("failed to create user: %w", entity.ErrAlreadyExists)},
+			wantErrType: customerrors.Conflict,
+			wantErr:     true,
+		},
+		{
+			name: "error when email is re-registered before the revert",
+			in: &CreateUserInput{
+				Name:     "test",
+				Handle:   "test",
+				Email:    "test@test.com",
+				Password: "password",
+			},
+			reservedReturn: []interface{}{&entity.EmailChange{UserID: 2, OldEmail: "test@test.com"}, nil},
+			wantErrType:    customerrors.Conflict,
+			wantErr:        true,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			var mockRepo mockUserRepo
-			mockRepo.On("Create", mock.Anything).Return(test.mockReturn...)
+			var mockChangeRepo mockEmailChangeRepo
+			if test.reservedReturn == nil {
+				test.reservedReturn = []interface{}{nil, nil}
+			}
+			mockChangeRepo.On("FindRevertableByOldEmail", test.in.Email, mock.Anything).Return(test.reservedReturn...)
+			if test.mockReturn != nil {
+				mockRepo.On("Create", mock.Anything).Return(test.mockReturn...)
+			}
 
-			u := &UserUseCase{UserRepo: &mockRepo}
+			u := NewUserUseCase(&mockRepo, &mockChangeRepo)
 			userResponse, err := u.CreateUser(test.in)
-			if test.wantErr && test.mockReturn[0] == nil {
+			if test.wantErr {
 				assert.NotNil(t, err)
+				if test.wantErrType != customerrors.BadRequest {
+					assert.Equal(t, test.wantErrType, err.Type)
+				}
+				if test.reservedReturn[0] != nil {
+					mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+				}
 			} else {
 				user, _ := test.mockReturn[0].(*entity.User)
 				assert.Nil(t, err)
@@ -113,7 +186,7 @@ func TestCreateUser(t *testing.T) {
 
 func TestAuthenticateUser(t *testing.T) {
 	plainPassword := "password"
-	user, _ := entity.NewUser("test", "test@test.com", plainPassword)
+	user, _ := entity.NewUser("test", "test", "test@test.com", plainPassword)
 
 	tests := []struct {
 		name       string
@@ -231,39 +304,93 @@ func TestReadUser(t *testing.T) {
 	}
 }
 
-func TestReadAllUsers(t *testing.T) {
+func TestReadUsers(t *testing.T) {
+	users := []*entity.User{
+		{Model: gorm.Model{ID: 1}, Name: "test", Handle: "test"},
+		{Model: gorm.Model{ID: 2}, Name: "test2", Handle: "test2"},
+		{Model: gorm.Model{ID: 3}, Name: "test3", Handle: "test3"},
+	}
+
 	tests := []struct {
-		name       string
-		mockReturn []interface{}
-		wantErr    bool
+		name           string
+		in             *ListUsersInput
+		mockReturn     []interface{}
+		wantQuery      *entity.UserListQuery
+		wantUsers      int
+		wantNextCursor bool
+		wantErr        bool
 	}{
 		{
-			name: "success",
-			mockReturn: []interface{}{
-				[]*entity.User{
-					{
-						Name:     "test",
-						Email:    "test@test.com",
-						Password: "password",
-					},
-					{
-						Name:     "test2",
-						Email:    "test2test.com",
-						Password: "password2",
-					},
-				},
-				nil,
+			name:           "success with default page size",
+			in:             &ListUsersInput{},
+			mockReturn:     []interface{}{users, nil},
+			wantQuery:      &entity.UserListQuery{SortBy: entity.UserSortByID, Limit: DefaultUsersPageSize + 1},
+			wantUsers:      3,
+			wantNextCursor: false,
+		},
+		{
+			name:           "success with next page",
+			in:             &ListUsersInput{Query: " te ", Limit: 2, Sort: "name", Order: "desc"},
+			mockReturn:     []interface{}{users, nil},
+			wantQuery:      &entity.UserListQuery{Prefix: "te", SortBy: entity.UserSortByName, Desc: true, Limit: 3},
+			wantUsers:      2,
+			wantNextCursor: true,
+		},
+		{
+			name:       "success with cursor",
+			in:         &ListUsersInput{Cursor: encodeUserCursor(&entity.UserCursor{Value: "test", ID: 1}), Sort: "handle"},
+			mockReturn: []interface{}{users[1:], nil},
+			wantQuery: &entity.UserListQuery{
+				SortBy: entity.UserSortByHandle,
+				After:  &entity.UserCursor{Value: "test", ID: 1},
+				Limit:  DefaultUsersPageSize + 1,
 			},
-			wantErr: false,
+			wantUsers: 2,
+		},
+		{
+			name:       "limit is capped",
+			in:         &ListUsersInput{Limit: 10000},
+			mockReturn: []interface{}{users, nil},
+			wantQuery:  &entity.UserListQuery{SortBy: entity.UserSortByID, Limit: MaxUsersPageSize + 1},
+			wantUsers:  3,
 		},
 		{
 			name:       "not found",
+			in:         &ListUsersInput{},
 			mockReturn: []interface{}{nil, nil},
-			wantErr:    false,
+			wantQuery:  &entity.UserListQuery{SortBy: entity.UserSortByID, Limit: DefaultUsersPageSize + 1},
+			wantUsers:  0,
+		},
+		{
+			name:    "error invalid sort field",
+			in:      &ListUsersInput{Sort: "password"},
+			wantErr: true,
+		},
+		{
+			name:    "error invalid sort order",
+			in:      &ListUsersInput{Order: "sideways"},
+			wantErr: true,
+		},
+		{
+			name:    "error negative limit",
+			in:      &ListUsersInput{Limit: -1},
+			wantErr: true,
+		},
+		{
+			name:    "error invalid cursor",
+			in:      &ListUsersInput{Cursor: "not a cursor"},
+			wantErr: true,
+		},
+		{
+			name:    "error invalid cursor time",
+			in:      &ListUsersInput{Cursor: encodeUserCursor(&entity.UserCursor{Value: "test", ID: 1}), Sort: "created_at"},
+			wantErr: true,
 		},
 		{
 			name:       "error when finding users",
+			in:         &ListUsersInput{},
 			mockReturn: []interface{}{nil, errors.New("error")},
+			wantQuery:  &entity.UserListQuery{SortBy: entity.UserSortByID, Limit: DefaultUsersPageSize + 1},
 			wantErr:    true,
 		},
 	}
@@ -271,23 +398,25 @@ func TestReadAllUsers(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			var mockRepo mockUserRepo
-			mockRepo.On("FindAll").Return(test.mockReturn...)
+			if test.wantQuery != nil {
+				mockRepo.On("FindPage", test.wantQuery).Return(test.mockReturn...)
+			}
 
 			u := &UserUseCase{UserRepo: &mockRepo}
-			usersResponse, err := u.ReadAllUsers()
+			usersResponse, err := u.ReadUsers(test.in)
 			if test.wantErr {
 				assert.NotNil(t, err)
-			} else if test.mockReturn[0] == nil {
-				assert.Nil(t, err)
-				assert.Equal(t, 0, len(usersResponse.Users))
 			} else {
 				assert.Nil(t, err)
-				assert.NotNil(t, usersResponse)
-				assert.Equal(t, 2, len(usersResponse.Users))
-				assert.Equal(t, test.mockReturn[0].([]*entity.User)[0].Name, usersResponse.Users[0].Name)
-				assert.Equal(t, test.mockReturn[0].([]*entity.User)[1].Name, usersResponse.Users[1].Name)
-				mockRepo.AssertExpectations(t)
+				assert.Equal(t, test.wantUsers, len(usersResponse.Users))
+				assert.Equal(t, test.wantNextCursor, usersResponse.NextCursor != "")
+				if test.wantNextCursor {
+					cursor, decodeErr := decodeUserCursor(usersResponse.NextCursor)
+					assert.NoError(t, decodeErr)
+					assert.Equal(t, usersResponse.Users[test.wantUsers-1].ID, cursor.ID)
+				}
 			}
+			mockRepo.AssertExpectations(t)
 		})
 	}
 }
@@ -298,14 +427,15 @@ func TestUpdateUser(t *testing.T) {
 		inUserInput      *UpdateUserInput
 		findMockReturn   []interface{}
 		updateMockReturn error
+		wantErrType      customerrors.CustomErrorType
 		wantErr          bool
 	}{
 		{
 			name: "success",
 			inUserInput: &UpdateUserInput{
 				UserID: "1",
-				Name:   "test",
-				Email:  "update@test.com",
+				Name:   "updated",
+				Email:  "test@test.com",
 			},
 			findMockReturn: []interface{}{
 				&entity.User{
@@ -327,6 +457,7 @@ func TestUpdateUser(t *testing.T) {
 			},
 			findMockReturn:   []interface{}{nil, nil},
 			updateMockReturn: errors.New("error"),
+			wantErrType:      customerrors.NotFound,
 			wantErr:          true,
 		},
 		{
@@ -338,10 +469,29 @@ func TestUpdateUser(t *testing.T) {
 			},
 			findMockReturn:   []interface{}{nil, nil},
 			updateMockReturn: nil,
+			wantErrType:      customerrors.NotFound,
 			wantErr:          true,
 		},
 		{
-			name: "error when updating user",
+			name: "error when email is missing",
+			inUserInput: &UpdateUserInput{
+				UserID: "1",
+				Name:   "updated",
+			},
+			findMockReturn: []interface{}{
+				&entity.User{
+					Name:     "test",
+					Email:    "test@test.com",
+					Password: "password",
+				},
+				nil,
+			},
+			updateMockReturn: nil,
+			wantErrType:      customerrors.BadRequest,
+			wantErr:          true,
+		},
+		{
+			name: "error when email is changed",
 			inUserInput: &UpdateUserInput{
 				UserID: "1",
 				Name:   "test",
@@ -355,7 +505,46 @@ func TestUpdateUser(t *testing.T) {
 				},
 				nil,
 			},
+			updateMockReturn: nil,
+			wantErrType:      customerrors.BadRequest,
+			wantErr:          true,
+		},
+		{
+			name: "error when updating user",
+			inUserInput: &UpdateUserInput{
+				UserID: "1",
+				Name:   "updated",
+				Email:  "test@test.com",
+			},
+			findMockReturn: []interface{}{
+				&entity.User{
+					Name:     "test",
+					Email:    "test@test.com",
+					Password: "password",
+				},
+				nil,
+			},
 			updateMockReturn: errors.New("error"),
+			wantErrType:      customerrors.InternalServerError,
+			wantErr:          true,
+		},
+		{
+			name: "error when a unique field conflicts on update",
+			inUserInput: &UpdateUserInput{
+				UserID: "1",
+				Name:   "updated",
+				Email:  "test@test.com",
+			},
+			findMockReturn: []interface{}{
+				&entity.User{
+					Name:     "test",
+					Email:    "test@test.com",
+					Password: "password",
+				},
+				nil,
+			},
+			updateMockReturn: fmt.Errorf("failed to update user: %w", entity.ErrAlreadyExists),
+			wantErrType:      customerrors.Conflict,
 			wantErr:          true,
 		},
 	}
@@ -367,17 +556,275 @@ func TestUpdateUser(t *testing.T) {
 			mockRepo.On("Update", mock.Anything).Return(test.updateMockReturn)
 
 			u := &UserUseCase{UserRepo: &mockRepo}
-			err := u.UpdateUser(test.inUserInput)
+			userResponse, err := u.UpdateUser(test.inUserInput)
 			if test.wantErr {
 				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
 			} else {
 				assert.Nil(t, err)
+				assert.Equal(t, test.inUserInput.Name, userResponse.Name)
 				mockRepo.AssertExpectations(t)
 			}
 		})
 	}
 }
 
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name             string
+		in               *PatchUserInput
+		findMockReturn   []interface{}
+		updateMockReturn error
+		wantName         string
+		wantEmail        string
+		wantFields       []string
+		wantErr          bool
+	}{
+		{
+			name: "success to update only name",
+			in: &PatchUserInput{
+				UserID: "1",
+				Name:   patch.NewField("updated"),
+			},
+			findMockReturn: []interface{}{
+				&entity.User{Name: "test", Email: "test@test.com"},
+				nil,
+			},
+			updateMockReturn: nil,
+			wantName:         "updated",
+			wantEmail:        "test@test.com",
+			wantErr:          false,
+		},
+		{
+			name: "success with empty patch",
+			in:   &PatchUserInput{UserID: "1"},
+			findMockReturn: []interface{}{
+				&entity.User{Name: "test", Email: "test@test.com"},
+				nil,
+			},
+			updateMockReturn: nil,
+			wantName:         "test",
+			wantEmail:        "test@test.com",
+			wantErr:          false,
+		},
+		{
+			name: "error when name is null",
+			in: &PatchUserInput{
+				UserID: "1",
+				Name:   patch.Field[string]{Set: true, Null: true},
+			},
+			findMockReturn: []interface{}{
+				&entity.User{Name: "test", Email: "test@test.com"},
+				nil,
+			},
+			wantFields: []string{"name"},
+			wantErr:    true,
+		},
+		{
+			name: "error when every field is invalid",
+			in: &PatchUserInput{
+				UserID: "1",
+				Name:   patch.NewField(""),
+				Email:  patch.NewField("invalid"),
+			},
+			findMockReturn: []interface{}{
+				&entity.User{Name: "test", Email: "test@test.com"},
+				nil,
+			},
+			wantFields: []string{"name", "email"},
+			wantErr:    true,
+		},
+		{
+			name: "error when user not found",
+			in: &PatchUserInput{
+				UserID: "1",
+				Name:   patch.NewField("updated"),
+			},
+			findMockReturn: []interface{}{nil, nil},
+			wantErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockRepo mockUserRepo
+			mockRepo.On("FindByID", test.in.UserID).Return(test.findMockReturn...)
+			mockRepo.On("Update", mock.Anything).Return(test.updateMockReturn)
+
+			u := &UserUseCase{UserRepo: &mockRepo}
+			userResponse, err := u.PatchUser(test.in)
+			if test.wantErr {
+				assert.NotNil(t, err)
+				for _, field := range test.wantFields {
+					assert.Contains(t, err.Fields, field)
+				}
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, test.wantName, userResponse.Name)
+				updated := test.findMockReturn[0].(*entity.User)
+				assert.Equal(t, test.wantEmail, updated.Email)
+				mockRepo.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name                 string
+		in                   *UpdateProfileInput
+		findMockReturn       []interface{}
+		findHandleMockReturn []interface{}
+		updateMockReturn     error
+		wantErrType          customerrors.CustomErrorType
+		wantFields           []string
+		wantErr              bool
+	}{
+		{
+			name: "success",
+			in: &UpdateProfileInput{
+				UserID:      "1",
+				Handle:      str("new_handle"),
+				DisplayName: str("Test User"),
+				Timezone:    str("Asia/Tokyo"),
+			},
+			findMockReturn: []interface{}{
+				&entity.User{Name: "test", Handle: "test", Timezone: "UTC", Locale: "en"},
+				nil,
+			},
+			findHandleMockReturn: []interface{}{nil, nil},
+			updateMockReturn:     nil,
+			wantErr:              false,
+		},
+		{
+			name: "success keeping own handle",
+			in: &UpdateProfileInput{
+				UserID: "1",
+				Handle: str("test"),
+			},
+			findMockReturn: []interface{}{
+				&entity.User{Name: "test", Handle: "test", Timezone: "UTC", Locale: "en"},
+				nil,
+			},
+			updateMockReturn: nil,
+			wantErr:          false,
+		},
+		{
+			name:           "error when user not found",
+			in:             &UpdateProfileInput{UserID: "1"},
+			findMockReturn: []interface{}{nil, nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when finding user",
+			in:             &UpdateProfileInput{UserID: "1"},
+			findMockReturn: []interface{}{nil, errors.New("error")},
+			wantErrType:    customerrors.InternalServerError,
+			wantErr:        true,
+		},
+		{
+			name: "error when handle is taken",
+			in: &UpdateProfileInput{
+				UserID: "1",
+				Handle: str("taken"),
+			},
+			findMockReturn: []interface{}{
+				&entity.User{Name: "test", Handle: "test"},
+				nil,
+			},
+			findHandleMockReturn: []interface{}{
+				&entity.User{Name: "other", Handle: "taken"},
+				nil,
+			},
+			wantErrType: customerrors.Conflict,
+			wantErr:     true,
+		},
+		{
+			name: "error when profile is invalid",
+			in: &UpdateProfileInput{
+				UserID:   "1",
+				Timezone: str("Mars/Olympus"),
+			},
+			findMockReturn: []interface{}{
+				&entity.User{Name: "test", Handle: "test"},
+				nil,
+			},
+			wantErrType: customerrors.BadRequest,
+			wantFields:  []string{"timezone"},
+			wantErr:     true,
+		},
+		{
+			name: "error for each invalid field without looking up the handle",
+			in: &UpdateProfileInput{
+				UserID: "1",
+				Handle: str("Not A Handle"),
+				Bio:    str(strings.Repeat("a", 501)),
+				Locale: str("en-abcdefgh-abcdefgh-abcdefgh-abcdefgh"),
+			},
+			findMockReturn: []interface{}{
+				&entity.User{Name: "test", Handle: "test"},
+				nil,
+			},
+			wantErrType: customerrors.BadRequest,
+			wantFields:  []string{"handle", "bio", "locale"},
+			wantErr:     true,
+		},
+		{
+			name: "error when updating user",
+			in: &UpdateProfileInput{
+				UserID: "1",
+				Bio:    str("hello"),
+			},
+			findMockReturn: []interface{}{
+				&entity.User{Name: "test", Handle: "test"},
+				nil,
+			},
+			updateMockReturn: errors.New("error"),
+			wantErrType:      customerrors.InternalServerError,
+			wantErr:          true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockRepo mockUserRepo
+			mockRepo.On("FindByID", test.in.UserID).Return(test.findMockReturn...)
+			if test.findHandleMockReturn != nil {
+				mockRepo.On("FindByHandle", *test.in.Handle).Return(test.findHandleMockReturn...)
+			}
+			mockRepo.On("Update", mock.Anything).Return(test.updateMockReturn)
+
+			u := &UserUseCase{UserRepo: &mockRepo}
+			userResponse, err := u.UpdateProfile(test.in)
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+				assert.Equal(t, len(test.wantFields), len(err.Fields))
+				for _, field := range test.wantFields {
+					assert.Contains(t, err.Fields, field)
+				}
+				if test.findHandleMockReturn == nil {
+					mockRepo.AssertNotCalled(t, "FindByHandle", mock.Anything)
+				}
+			} else {
+				assert.Nil(t, err)
+				if test.in.Handle != nil {
+					assert.Equal(t, *test.in.Handle, userResponse.Handle)
+				}
+				if test.in.DisplayName != nil {
+					assert.Equal(t, *test.in.DisplayName, userResponse.DisplayName)
+				}
+				if test.in.Timezone != nil {
+					assert.Equal(t, *test.in.Timezone, userResponse.Timezone)
+				}
+			}
+		})
+	}
+}
+
 func TestDestroyUser(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -446,3 +893,117 @@ func TestDestroyUser(t *testing.T) {
 		})
 	}
 }
+
+func TestRestoreUser(t *testing.T) {
+	newDeletedUser := func(deletedAt time.Time) *entity.User {
+		user, _ := entity.NewUser("test", "test", "test@test.com", "password")
+		user.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+		return user
+	}
+
+	tests := []struct {
+		name              string
+		in                *AuthenticateUserInput
+		findMockReturn    []interface{}
+		restoreMockReturn error
+		wantErrType       customerrors.CustomErrorType
+		wantErr           bool
+	}{
+		{
+			name:              "success",
+			in:                &AuthenticateUserInput{Email: "test@test.com", Password: "password"},
+			findMockReturn:    []interface{}{newDeletedUser(time.Now().Add(-time.Hour)), nil},
+			restoreMockReturn: nil,
+			wantErr:           false,
+		},
+		{
+			name:           "error when no deleted user",
+			in:             &AuthenticateUserInput{Email: "test@test.com", Password: "password"},
+			findMockReturn: []interface{}{nil, nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when past retention",
+			in:             &AuthenticateUserInput{Email: "test@test.com", Password: "password"},
+			findMockReturn: []interface{}{newDeletedUser(time.Now().Add(-entity.DeletedAccountRetention - time.Hour)), nil},
+			wantErrType:    customerrors.NotFound,
+			wantErr:        true,
+		},
+		{
+			name:           "error when password is wrong",
+			in:             &AuthenticateUserInput{Email: "test@test.com", Password: "wrong"},
+			findMockReturn: []interface{}{newDeletedUser(time.Now().Add(-time.Hour)), nil},
+			wantErrType:    customerrors.InvalidCredentials,
+			wantErr:        true,
+		},
+		{
+			name:              "error when email was registered again",
+			in:                &AuthenticateUserInput{Email: "test@test.com", Password: "password"},
+			findMockReturn:    []interface{}{newDeletedUser(time.Now().Add(-time.Hour)), nil},
+			restoreMockReturn: fmt.Errorf("failed to restore user: %w", entity.ErrAlreadyExists),
+			wantErrType:       customerrors.Conflict,
+			wantErr:           true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockRepo mockUserRepo
+			mockRepo.On("FindDeletedByEmail", test.in.Email).Return(test.findMockReturn...)
+			mockRepo.On("Restore", mock.Anything).Return(test.restoreMockReturn)
+
+			u := &UserUseCase{UserRepo: &mockRepo}
+			userResponse, err := u.RestoreUser(test.in)
+			if test.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, test.wantErrType, err.Type)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, "test", userResponse.Name)
+				mockRepo.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockReturn []interface{}
+		wantPurged int64
+		wantErr    bool
+	}{
+		{
+			name:       "success",
+			mockReturn: []interface{}{int64(2), nil},
+			wantPurged: 2,
+			wantErr:    false,
+		},
+		{
+			name:       "error when purging",
+			mockReturn: []interface{}{int64(0), errors.New("error")},
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mockRepo mockUserRepo
+			mockRepo.On("PurgeDeletedBefore", mock.MatchedBy(func(cutoff time.Time) bool {
+				// The cutoff is the retention period before now
+				return time.Since(cutoff.Add(entity.DeletedAccountRetention)) < time.Minute
+			})).Return(test.mockReturn...)
+
+			u := &UserUseCase{UserRepo: &mockRepo}
+			purged, err := u.PurgeDeletedUsers()
+			if test.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, test.wantPurged, purged)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 
@@ -13,12 +14,15 @@ const (
 	BadRequest CustomErrorType = iota
 	InvalidCredentials
 	NotFound
+	Conflict
 	InternalServerError
 )
 
 type CustomError struct {
 	Type  CustomErrorType
 	Error error
+	// Fields maps request field names to what is wrong with them
+	Fields map[string]string
 }
 
 var errorDetails = map[CustomErrorType]struct {
@@ -28,6 +32,7 @@ var errorDetails = map[CustomErrorType]struct {
 	BadRequest:          {Message: "invalid request", Status: http.StatusBadRequest},
 	InvalidCredentials:  {Message: "invalid credentials", Status: http.StatusUnauthorized},
 	NotFound:            {Message: "resource not found", Status: http.StatusNotFound},
+	Conflict:            {Message: "resource already exists", Status: http.StatusConflict},
 	InternalServerError: {Message: "internal server error", Status: http.StatusInternalServerError},
 }
 
@@ -38,10 +43,23 @@ func NewCustomError(t CustomErrorType, err error) *CustomError {
 	}
 }
 
+// NewValidationError creates a bad request error reporting invalid request fields
+func NewValidationError(fields map[string]string) *CustomError {
+	return &CustomError{
+		Type:   BadRequest,
+		Error:  fmt.Errorf("invalid fields: %v", fields),
+		Fields: fields,
+	}
+}
+
 func (e *CustomError) ErrorResponse(c echo.Context) error {
 	log.Println(e.Error)
 	message, status := e.getErrorDetails()
-	return c.JSON(status, responseData(message, status))
+	data := responseData(message, status)
+	if len(e.Fields) > 0 {
+		data["fields"] = e.Fields
+	}
+	return c.JSON(status, data)
 }
 
 func (e *CustomError) getErrorDetails() (string, int) {
@@ -2,8 +2,11 @@ package errors
 
 import (
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,6 +29,12 @@ func TestErrorResponse(t *testing.T) {
 			expectedMessage: "invalid credentials",
 			expectedStatus:  401,
 		},
+		{
+			name:            "conflict",
+			customError:     &CustomError{Type: Conflict, Error: errors.New("errors")},
+			expectedMessage: "resource already exists",
+			expectedStatus:  409,
+		},
 		{
 			name:            "internal server error",
 			customError:     &CustomError{Type: InternalServerError, Error: errors.New("errors")},
@@ -42,3 +51,35 @@ func TestErrorResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorResponseWithFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		customError  *CustomError
+		expectedBody string
+	}{
+		{
+			name:         "without fields",
+			customError:  NewCustomError(BadRequest, errors.New("errors")),
+			expectedBody: `{"message":"invalid request","status":400}`,
+		},
+		{
+			name:         "with fields",
+			customError:  NewValidationError(map[string]string{"name": "must not be empty"}),
+			expectedBody: `{"fields":{"name":"must not be empty"},"message":"invalid request","status":400}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			test.customError.ErrorResponse(c)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, test.expectedBody, rec.Body.String())
+		})
+	}
+}
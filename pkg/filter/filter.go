@@ -0,0 +1,241 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"chatapp/pkg/mention"
+)
+
+// Rules name the built-in filters in a Rejection, custom filters use their own
+const (
+	RuleWordList    = "word_list"
+	RuleLinkDomain  = "link_domain"
+	RuleMaxLength   = "max_length"
+	RuleMaxMentions = "max_mentions"
+	RuleDuplicate   = "duplicate"
+)
+
+// Message is an outgoing message as the filters see it before it is stored
+type Message struct {
+	SenderID uint
+	Text     string
+	SentAt   time.Time
+	// Flags are the reasons filters gave to have the message reviewed, it is still sent
+	Flags []string
+}
+
+// Filter checks a message and may rewrite its text or flag it.
+// Returning an error blocks the message, a *Rejection tells the sender why.
+type Filter interface {
+	Filter(msg *Message) error
+}
+
+// Func adapts a function to a Filter, so that a filter can be added without a type
+type Func func(msg *Message) error
+
+func (f Func) Filter(msg *Message) error {
+	return f(msg)
+}
+
+// Chain runs filters in order and stops at the first one that blocks the message
+type Chain []Filter
+
+func (c Chain) Filter(msg *Message) error {
+	for _, filter := range c {
+		if err := filter.Filter(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rejection is the error of a filter that blocked a message, its message is meant for the sender
+type Rejection struct {
+	Rule    string
+	Message string
+}
+
+func (e *Rejection) Error() string {
+	return e.Message
+}
+
+// Action is what a word list does with a message containing one of its words
+type Action int
+
+const (
+	// Block rejects the message
+	Block Action = iota
+	// Mask replaces the words with asterisks
+	Mask
+	// Flag sends the message as is and flags it for review
+	Flag
+)
+
+// WordList matches whole words case-insensitively
+type WordList struct {
+	Action  Action
+	pattern *regexp.Regexp
+}
+
+// NewWordList creates a word list filter, an empty list lets every message through
+func NewWordList(words []string, action Action) *WordList {
+	var quoted []string
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+
+	list := &WordList{Action: action}
+	if len(quoted) > 0 {
+		list.pattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	}
+	return list
+}
+
+func (f *WordList) Filter(msg *Message) error {
+	if f.pattern == nil || !f.pattern.MatchString(msg.Text) {
+		return nil
+	}
+
+	switch f.Action {
+	case Mask:
+		msg.Text = f.pattern.ReplaceAllStringFunc(msg.Text, func(word string) string {
+			return strings.Repeat("*", utf8.RuneCountInString(word))
+		})
+	case Flag:
+		msg.Flags = append(msg.Flags, RuleWordList)
+	default:
+		return &Rejection{Rule: RuleWordList, Message: "message contains a blocked word"}
+	}
+	return nil
+}
+
+// linkPattern finds http and https links, the only ones the Markdown renderer turns into links
+var linkPattern = regexp.MustCompile(`(?i)https?://[^\s<>()\[\]]+`)
+
+// LinkDomains checks the domains of the links in a message. A domain also covers its subdomains.
+// When Allow is not empty only its domains can be linked, Deny is checked either way.
+type LinkDomains struct {
+	Allow []string
+	Deny  []string
+}
+
+func (f *LinkDomains) Filter(msg *Message) error {
+	for _, link := range linkPattern.FindAllString(msg.Text, -1) {
+		u, err := url.Parse(link)
+		if err != nil || u.Hostname() == "" {
+			return &Rejection{Rule: RuleLinkDomain, Message: fmt.Sprintf("invalid link: %s", link)}
+		}
+
+		host := strings.ToLower(u.Hostname())
+		if matchesDomain(host, f.Deny) || (len(f.Allow) > 0 && !matchesDomain(host, f.Allow)) {
+			return &Rejection{Rule: RuleLinkDomain, Message: fmt.Sprintf("links to %s are not allowed", host)}
+		}
+	}
+	return nil
+}
+
+// MaxLength limits the number of characters of a message
+type MaxLength struct {
+	Max int
+}
+
+func (f *MaxLength) Filter(msg *Message) error {
+	if utf8.RuneCountInString(msg.Text) > f.Max {
+		return &Rejection{Rule: RuleMaxLength, Message: fmt.Sprintf("message must be at most %d characters", f.Max)}
+	}
+	return nil
+}
+
+// MaxMentions limits the number of users and groups mentioned in a message, @here and @room count as one each
+type MaxMentions struct {
+	Max int
+}
+
+func (f *MaxMentions) Filter(msg *Message) error {
+	mentions := mention.Parse(msg.Text)
+	count := len(mentions.Handles)
+	if mentions.Here {
+		count++
+	}
+	if mentions.Room {
+		count++
+	}
+
+	if count > f.Max {
+		return &Rejection{Rule: RuleMaxMentions, Message: fmt.Sprintf("message must mention at most %d users", f.Max)}
+	}
+	return nil
+}
+
+// DuplicateFlood blocks a sender who sends the same text more than Max times within Window.
+// Texts are compared ignoring case and surrounding spaces. The history is kept in memory,
+// so with several instances each one counts the messages it filtered itself.
+type DuplicateFlood struct {
+	Max    int
+	Window time.Duration
+
+	mu      sync.Mutex
+	history map[uint][]sentText
+}
+
+type sentText struct {
+	text   string
+	sentAt time.Time
+}
+
+// NewDuplicateFlood creates a duplicate flood filter
+func NewDuplicateFlood(max int, window time.Duration) *DuplicateFlood {
+	return &DuplicateFlood{
+		Max:     max,
+		Window:  window,
+		history: map[uint][]sentText{},
+	}
+}
+
+func (f *DuplicateFlood) Filter(msg *Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Forget what is out of the window, so the history of a sender stays short
+	since := msg.SentAt.Add(-f.Window)
+	recent := f.history[msg.SenderID][:0]
+	for _, sent := range f.history[msg.SenderID] {
+		if sent.sentAt.After(since) {
+			recent = append(recent, sent)
+		}
+	}
+
+	text := strings.ToLower(strings.TrimSpace(msg.Text))
+	count := 0
+	for _, sent := range recent {
+		if sent.text == text {
+			count++
+		}
+	}
+	if count >= f.Max {
+		f.history[msg.SenderID] = recent
+		return &Rejection{Rule: RuleDuplicate, Message: "the same message was sent too many times, wait before sending it again"}
+	}
+
+	f.history[msg.SenderID] = append(recent, sentText{text: text, sentAt: msg.SentAt})
+	return nil
+}
+
+// matchesDomain reports whether the host is one of the domains or a subdomain of one
+func matchesDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,344 @@
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	errCustom := errors.New("custom")
+	var called []string
+	record := func(name string, err error) Filter {
+		return Func(func(msg *Message) error {
+			called = append(called, name)
+			return err
+		})
+	}
+
+	tests := []struct {
+		name       string
+		chain      Chain
+		wantErr    error
+		wantCalled []string
+	}{
+		{
+			name:       "empty chain",
+			chain:      Chain{},
+			wantErr:    nil,
+			wantCalled: nil,
+		},
+		{
+			name:       "every filter passes",
+			chain:      Chain{record("first", nil), record("second", nil)},
+			wantErr:    nil,
+			wantCalled: []string{"first", "second"},
+		},
+		{
+			name:       "stops at the first error",
+			chain:      Chain{record("first", errCustom), record("second", nil)},
+			wantErr:    errCustom,
+			wantCalled: []string{"first"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called = nil
+			err := test.chain.Filter(&Message{Text: "hello"})
+			assert.Equal(t, test.wantErr, err)
+			assert.Equal(t, test.wantCalled, called)
+		})
+	}
+}
+
+func TestChainPassesRewrittenText(t *testing.T) {
+	chain := Chain{
+		NewWordList([]string{"darn"}, Mask),
+		NewWordList([]string{"darn"}, Block),
+	}
+
+	msg := &Message{Text: "darn it"}
+	assert.NoError(t, chain.Filter(msg))
+	assert.Equal(t, "**** it", msg.Text)
+}
+
+func TestWordList(t *testing.T) {
+	tests := []struct {
+		name      string
+		words     []string
+		action    Action
+		text      string
+		wantText  string
+		wantFlags []string
+		wantRule  string
+	}{
+		{
+			name:     "no match",
+			words:    []string{"darn"},
+			action:   Block,
+			text:     "hello there",
+			wantText: "hello there",
+		},
+		{
+			name:     "only whole words match",
+			words:    []string{"darn"},
+			action:   Block,
+			text:     "darned darnit",
+			wantText: "darned darnit",
+		},
+		{
+			name:     "empty list",
+			words:    []string{"", " "},
+			action:   Block,
+			text:     "darn",
+			wantText: "darn",
+		},
+		{
+			name:     "block ignoring case",
+			words:    []string{"darn"},
+			action:   Block,
+			text:     "DARN it",
+			wantText: "DARN it",
+			wantRule: RuleWordList,
+		},
+		{
+			name:     "mask every match",
+			words:    []string{"darn", "héck"},
+			action:   Mask,
+			text:     "darn, Héck and darn",
+			wantText: "****, **** and ****",
+		},
+		{
+			name:     "word with special characters",
+			words:    []string{"a.b"},
+			action:   Mask,
+			text:     "a.b axb",
+			wantText: "*** axb",
+		},
+		{
+			name:      "flag",
+			words:     []string{"darn"},
+			action:    Flag,
+			text:      "darn it",
+			wantText:  "darn it",
+			wantFlags: []string{RuleWordList},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg := &Message{Text: test.text}
+			err := NewWordList(test.words, test.action).Filter(msg)
+			assertRule(t, test.wantRule, err)
+			assert.Equal(t, test.wantText, msg.Text)
+			assert.Equal(t, test.wantFlags, msg.Flags)
+		})
+	}
+}
+
+func TestLinkDomains(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   *LinkDomains
+		text     string
+		wantRule string
+	}{
+		{
+			name:   "no link",
+			filter: &LinkDomains{Deny: []string{"example.com"}},
+			text:   "example.com is not a link",
+		},
+		{
+			name:     "denied domain",
+			filter:   &LinkDomains{Deny: []string{"example.com"}},
+			text:     "see https://example.com/page",
+			wantRule: RuleLinkDomain,
+		},
+		{
+			name:     "denied subdomain",
+			filter:   &LinkDomains{Deny: []string{"example.com"}},
+			text:     "see http://www.EXAMPLE.com",
+			wantRule: RuleLinkDomain,
+		},
+		{
+			name:   "domain that only ends the same",
+			filter: &LinkDomains{Deny: []string{"example.com"}},
+			text:   "see https://notexample.com",
+		},
+		{
+			name:   "allowed domain",
+			filter: &LinkDomains{Allow: []string{"example.com"}},
+			text:   "see https://docs.example.com/a and https://example.com:8080",
+		},
+		{
+			name:     "domain not allowed",
+			filter:   &LinkDomains{Allow: []string{"example.com"}},
+			text:     "see https://example.com and https://other.org",
+			wantRule: RuleLinkDomain,
+		},
+		{
+			name:     "denied within allowed",
+			filter:   &LinkDomains{Allow: []string{"example.com"}, Deny: []string{"bad.example.com"}},
+			text:     "see https://bad.example.com",
+			wantRule: RuleLinkDomain,
+		},
+		{
+			name:     "link in Markdown",
+			filter:   &LinkDomains{Deny: []string{"example.com"}},
+			text:     "[click](https://example.com)",
+			wantRule: RuleLinkDomain,
+		},
+		{
+			name:     "link without host",
+			filter:   &LinkDomains{},
+			text:     "see https://:80",
+			wantRule: RuleLinkDomain,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertRule(t, test.wantRule, test.filter.Filter(&Message{Text: test.text}))
+		})
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		wantRule string
+	}{
+		{
+			name: "at the limit",
+			text: strings.Repeat("a", 5),
+		},
+		{
+			name: "characters, not bytes",
+			text: strings.Repeat("é", 5),
+		},
+		{
+			name:     "over the limit",
+			text:     strings.Repeat("a", 6),
+			wantRule: RuleMaxLength,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertRule(t, test.wantRule, (&MaxLength{Max: 5}).Filter(&Message{Text: test.text}))
+		})
+	}
+}
+
+func TestMaxMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		wantRule string
+	}{
+		{
+			name: "at the limit",
+			text: "@alice @bob",
+		},
+		{
+			name: "repeated mention counts once",
+			text: "@alice @alice @bob",
+		},
+		{
+			name:     "over the limit",
+			text:     "@alice @bob @carol",
+			wantRule: RuleMaxMentions,
+		},
+		{
+			name:     "group mentions count",
+			text:     "@alice @here @room",
+			wantRule: RuleMaxMentions,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertRule(t, test.wantRule, (&MaxMentions{Max: 2}).Filter(&Message{Text: test.text}))
+		})
+	}
+}
+
+func TestDuplicateFlood(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type send struct {
+		senderID uint
+		text     string
+		after    time.Duration
+		wantRule string
+	}
+	tests := []struct {
+		name  string
+		sends []send
+	}{
+		{
+			name: "different texts",
+			sends: []send{
+				{senderID: 1, text: "hi", after: 0},
+				{senderID: 1, text: "hello", after: time.Second},
+				{senderID: 1, text: "hey", after: 2 * time.Second},
+			},
+		},
+		{
+			name: "same text too many times",
+			sends: []send{
+				{senderID: 1, text: "buy now", after: 0},
+				{senderID: 1, text: "BUY NOW ", after: time.Second},
+				{senderID: 1, text: "buy now", after: 2 * time.Second, wantRule: RuleDuplicate},
+			},
+		},
+		{
+			name: "senders are counted apart",
+			sends: []send{
+				{senderID: 1, text: "hi", after: 0},
+				{senderID: 1, text: "hi", after: time.Second},
+				{senderID: 2, text: "hi", after: 2 * time.Second},
+			},
+		},
+		{
+			name: "old messages leave the window",
+			sends: []send{
+				{senderID: 1, text: "hi", after: 0},
+				{senderID: 1, text: "hi", after: time.Second},
+				{senderID: 1, text: "hi", after: 2 * time.Second, wantRule: RuleDuplicate},
+				{senderID: 1, text: "hi", after: time.Minute + time.Second},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := NewDuplicateFlood(2, time.Minute)
+			for _, s := range test.sends {
+				msg := &Message{SenderID: s.senderID, Text: s.text, SentAt: start.Add(s.after)}
+				assertRule(t, s.wantRule, filter.Filter(msg))
+			}
+		})
+	}
+}
+
+// assertRule checks that err is nil when rule is empty, or a Rejection by the rule
+func assertRule(t *testing.T, rule string, err error) {
+	t.Helper()
+
+	if rule == "" {
+		assert.NoError(t, err)
+		return
+	}
+
+	var rejection *Rejection
+	if assert.True(t, errors.As(err, &rejection)) {
+		assert.Equal(t, rule, rejection.Rule)
+		assert.NotEmpty(t, rejection.Message)
+	}
+}
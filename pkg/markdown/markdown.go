@@ -0,0 +1,522 @@
+package markdown
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rendered is a message body written in Markdown with its HTML and plain text forms.
+// The source is what the user wrote and is what gets stored and edited, the other
+// forms can always be rendered again from it.
+type Rendered struct {
+	Source string
+	// HTML is safe to insert into a page as is, raw HTML in the source is escaped
+	HTML string
+	// Text is the content without formatting, for search and notifications
+	Text string
+}
+
+const (
+	// MaxSourceLength is the longest source in bytes that Render accepts
+	MaxSourceLength = 16 * 1024
+	// maxQuoteDepth is how deep quotes nest, further > markers are kept as text
+	maxQuoteDepth = 8
+)
+
+// ErrSourceTooLong is returned by Render for sources longer than MaxSourceLength
+var ErrSourceTooLong = errors.New("markdown source is too long")
+
+var (
+	bulletItemRegex  = regexp.MustCompile(`^[-*+](?: |$)`)
+	orderedItemRegex = regexp.MustCompile(`^(\d{1,9})[.)](?: |$)`)
+)
+
+// Render renders the supported Markdown subset: **bold**, *italics*, `code spans`,
+// fenced code blocks, [links](https://example.com), > quotes and - or 1. lists.
+// Single line breaks are kept as line breaks, the way chat messages are written.
+// Rendering takes time linear in the length of the source, which is limited to MaxSourceLength.
+func Render(source string) (*Rendered, error) {
+	if len(source) > MaxSourceLength {
+		return nil, ErrSourceTooLong
+	}
+
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+
+	r := &renderer{}
+	r.blocks(strings.Split(source, "\n"))
+
+	return &Rendered{
+		Source: source,
+		HTML:   strings.Join(r.html, "\n"),
+		Text:   strings.TrimSpace(strings.Join(r.text, "\n")),
+	}, nil
+}
+
+type renderer struct {
+	html []string
+	text []string
+	// depth is the number of quotes the blocks are nested in
+	depth int
+}
+
+// blocks renders the lines as a sequence of block elements
+func (r *renderer) blocks(lines []string) {
+	for i := 0; i < len(lines); {
+		line := unindent(lines[i])
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case fenceOf(line) != "":
+			i = r.codeBlock(lines, i)
+		case r.startsQuote(line):
+			i = r.quote(lines, i)
+		case bulletItemRegex.MatchString(line):
+			i = r.list(lines, i, false)
+		case orderedItemRegex.MatchString(line):
+			i = r.list(lines, i, true)
+		default:
+			i = r.paragraph(lines, i)
+		}
+	}
+}
+
+// codeBlock renders a fenced code block, an unclosed fence runs to the end of the message
+func (r *renderer) codeBlock(lines []string, start int) int {
+	line := unindent(lines[start])
+	fence := fenceOf(line)
+	language := strings.Fields(line[len(fence):])
+
+	var content []string
+	i := start + 1
+	for ; i < len(lines); i++ {
+		closing := unindent(lines[i])
+		if strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]+" \t") == "" {
+			i++
+			break
+		}
+		content = append(content, lines[i])
+	}
+
+	code := strings.Join(content, "\n")
+	class := ""
+	if len(language) > 0 && isLanguageName(language[0]) {
+		class = ` class="language-` + language[0] + `"`
+	}
+	r.html = append(r.html, "<pre><code"+class+">"+html.EscapeString(code)+"</code></pre>")
+	r.text = append(r.text, code)
+
+	return i
+}
+
+// quote renders consecutive quoted lines, the quote can hold any other block
+func (r *renderer) quote(lines []string, start int) int {
+	var content []string
+	i := start
+	for ; i < len(lines); i++ {
+		line := unindent(lines[i])
+		if !strings.HasPrefix(line, ">") {
+			break
+		}
+		line = strings.TrimPrefix(line[1:], " ")
+		content = append(content, line)
+	}
+
+	inner := &renderer{depth: r.depth + 1}
+	inner.blocks(content)
+	r.html = append(r.html, "<blockquote>"+strings.Join(inner.html, "\n")+"</blockquote>")
+	r.text = append(r.text, inner.text...)
+
+	return i
+}
+
+// list renders consecutive items of a bullet or an ordered list, one item per line
+func (r *renderer) list(lines []string, start int, ordered bool) int {
+	itemRegex, tag, attrs := bulletItemRegex, "ul", ""
+	if ordered {
+		itemRegex, tag = orderedItemRegex, "ol"
+		number, _ := strconv.Atoi(orderedItemRegex.FindStringSubmatch(unindent(lines[start]))[1])
+		if number != 1 {
+			attrs = ` start="` + strconv.Itoa(number) + `"`
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("<" + tag + attrs + ">")
+
+	i := start
+	for ; i < len(lines); i++ {
+		line := unindent(lines[i])
+		marker := itemRegex.FindString(line)
+		if marker == "" {
+			break
+		}
+
+		itemHTML, itemText := renderInline(line[len(marker):])
+		b.WriteString("<li>" + itemHTML + "</li>")
+		r.text = append(r.text, itemText)
+	}
+
+	b.WriteString("</" + tag + ">")
+	r.html = append(r.html, b.String())
+
+	return i
+}
+
+// paragraph renders lines up to a blank line or the start of another block
+func (r *renderer) paragraph(lines []string, start int) int {
+	var content []string
+	i := start
+	for ; i < len(lines); i++ {
+		line := unindent(lines[i])
+		if strings.TrimSpace(line) == "" || (i > start && r.startsBlock(line)) {
+			break
+		}
+		content = append(content, strings.TrimSpace(line))
+	}
+
+	paragraphHTML, paragraphText := renderInline(strings.Join(content, "\n"))
+	r.html = append(r.html, "<p>"+paragraphHTML+"</p>")
+	r.text = append(r.text, paragraphText)
+
+	return i
+}
+
+// renderInline renders the spans of a block and returns its HTML and plain text
+func renderInline(s string) (string, string) {
+	r := &inlineRenderer{closers: matchBrackets(s), unclosed: map[delimiter]int{}}
+	r.render(s, 0)
+	return r.html.String(), r.text.String()
+}
+
+type inlineRenderer struct {
+	html   strings.Builder
+	text   strings.Builder
+	inLink bool
+	// closers maps each opening bracket or parenthesis to the one closing it
+	closers map[int]int
+	// unclosed maps emphasis delimiters to the position after which they have no closing run,
+	// so that a long row of unmatched delimiters is only scanned once
+	unclosed map[delimiter]int
+}
+
+// delimiter is an emphasis delimiter of a size, looked for up to an end
+type delimiter struct {
+	c    byte
+	size int
+	end  int
+}
+
+// render renders s from the index from. Nested spans are rendered from a prefix of the
+// block rather than a substring, so that indexes stay valid for closers.
+func (r *inlineRenderer) render(s string, from int) {
+	for i := from; i < len(s); {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && isPunctuation(s[i+1]):
+			r.literal(s[i+1 : i+2])
+			i += 2
+		case c == '\n':
+			r.html.WriteString("<br>")
+			r.text.WriteByte('\n')
+			i++
+		case c == '`':
+			i = r.codeSpan(s, i)
+		case c == '*' || c == '_':
+			i = r.emphasis(s, i)
+		case c == '[' && !r.inLink:
+			i = r.link(s, i)
+		default:
+			r.literal(s[i : i+1])
+			i++
+		}
+	}
+}
+
+// literal writes text as is, escaped in HTML
+func (r *inlineRenderer) literal(s string) {
+	r.html.WriteString(html.EscapeString(s))
+	r.text.WriteString(s)
+}
+
+func (r *inlineRenderer) codeSpan(s string, i int) int {
+	run := runLength(s, i, '`')
+	end := codeSpanEnd(s, i)
+	if end < 0 {
+		r.literal(s[i : i+run])
+		return i + run
+	}
+
+	code := s[i+run : end-run]
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+		code = code[1 : len(code)-1]
+	}
+	r.html.WriteString("<code>" + html.EscapeString(code) + "</code>")
+	r.text.WriteString(code)
+
+	return end
+}
+
+func (r *inlineRenderer) emphasis(s string, i int) int {
+	c := s[i]
+	run := runLength(s, i, c)
+	if !canOpen(s, i, run) {
+		r.literal(s[i : i+run])
+		return i + run
+	}
+
+	size, tag := 1, "em"
+	if run >= 2 {
+		size, tag = 2, "strong"
+	}
+
+	key := delimiter{c: c, size: size, end: len(s)}
+	end := -1
+	if after, ok := r.unclosed[key]; !ok || i+size < after {
+		end = findClosing(s, i+size, c, size)
+		if end < 0 {
+			r.unclosed[key] = i + size
+		}
+	}
+	if end <= i+size {
+		// Unmatched, the first delimiter is plain text and the rest may still open a span
+		r.literal(s[i : i+1])
+		return i + 1
+	}
+
+	r.html.WriteString("<" + tag + ">")
+	r.render(s[:end], i+size)
+	r.html.WriteString("</" + tag + ">")
+
+	return end + size
+}
+
+func (r *inlineRenderer) link(s string, i int) int {
+	closeBracket, ok := r.closers[i]
+	if !ok || closeBracket+1 >= len(s) || s[closeBracket+1] != '(' {
+		r.literal("[")
+		return i + 1
+	}
+	closeParen, ok := r.closers[closeBracket+1]
+	if !ok || closeParen >= len(s) {
+		r.literal("[")
+		return i + 1
+	}
+
+	href := strings.TrimSpace(s[closeBracket+2 : closeParen])
+	if href == "" || strings.ContainsAny(href, " \t\n") {
+		// Not a link target, link titles are not supported either
+		r.literal("[")
+		return i + 1
+	}
+
+	r.inLink = true
+	defer func() { r.inLink = false }()
+
+	if !isSafeURL(href) {
+		// Links to other schemes such as javascript: keep their label only
+		r.render(s[:closeBracket], i+1)
+		return closeParen + 1
+	}
+
+	r.html.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer">`)
+	textStart := r.text.Len()
+	r.render(s[:closeBracket], i+1)
+	r.html.WriteString("</a>")
+	if r.text.String()[textStart:] != href {
+		r.text.WriteString(" (" + href + ")")
+	}
+
+	return closeParen + 1
+}
+
+// findClosing finds the delimiter run closing an emphasis of the size opened before from.
+// Escaped delimiters and those inside code spans are skipped, as are runs of the other size
+// so that strong spans nest inside emphasis and the other way around.
+func findClosing(s string, from int, c byte, size int) int {
+	for j := from; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			if end := codeSpanEnd(s, j); end > 0 {
+				j = end
+			} else {
+				j += runLength(s, j, '`')
+			}
+			continue
+		case c:
+			run := runLength(s, j, c)
+			if canClose(s, j, run) {
+				if size == 1 && run == 1 {
+					return j
+				}
+				if size == 2 && run >= 2 {
+					// The extra delimiters of a longer run belong to a span nested inside
+					return j + run - 2
+				}
+			}
+			j += run
+			continue
+		}
+		j++
+	}
+
+	return -1
+}
+
+// codeSpanEnd returns the index after the backtick run closing the code span opened at i, or -1
+func codeSpanEnd(s string, i int) int {
+	run := runLength(s, i, '`')
+	for j := i + run; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		closing := runLength(s, j, '`')
+		if closing == run {
+			return j + closing
+		}
+		j += closing
+	}
+
+	return -1
+}
+
+// matchBrackets pairs the brackets and the parentheses of s in one pass. Escaped ones
+// and those inside code spans are skipped.
+func matchBrackets(s string) map[int]int {
+	closers := map[int]int{}
+	var brackets, parens []int
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			if end := codeSpanEnd(s, j); end > 0 {
+				j = end - 1
+			} else {
+				j += runLength(s, j, '`') - 1
+			}
+		case '[':
+			brackets = append(brackets, j)
+		case ']':
+			if len(brackets) > 0 {
+				closers[brackets[len(brackets)-1]] = j
+				brackets = brackets[:len(brackets)-1]
+			}
+		case '(':
+			parens = append(parens, j)
+		case ')':
+			if len(parens) > 0 {
+				closers[parens[len(parens)-1]] = j
+				parens = parens[:len(parens)-1]
+			}
+		}
+	}
+
+	return closers
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// canOpen reports whether a delimiter run can open an emphasis.
+// Underscores inside words, as in snake_case, never do.
+func canOpen(s string, i, run int) bool {
+	if i+run >= len(s) || isSpace(s[i+run]) {
+		return false
+	}
+	return s[i] != '_' || i == 0 || !isWordChar(s[i-1])
+}
+
+// canClose reports whether a delimiter run can close an emphasis
+func canClose(s string, i, run int) bool {
+	if i == 0 || isSpace(s[i-1]) {
+		return false
+	}
+	return s[i] != '_' || i+run >= len(s) || !isWordChar(s[i+run])
+}
+
+// isSafeURL reports whether a link target is an absolute http, https or mailto URL
+func isSafeURL(href string) bool {
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	}
+	return false
+}
+
+// fenceOf returns the fence opening a code block on the line, or an empty string
+func fenceOf(line string) string {
+	for _, c := range []byte{'`', '~'} {
+		run := runLength(line, 0, c)
+		if run < 3 {
+			continue
+		}
+		// Backticks in the info string would make it a code span instead
+		if c == '`' && strings.Contains(line[run:], "`") {
+			return ""
+		}
+		return line[:run]
+	}
+
+	return ""
+}
+
+// startsQuote reports whether the line is quoted and quotes can still nest here
+func (r *renderer) startsQuote(line string) bool {
+	return strings.HasPrefix(line, ">") && r.depth < maxQuoteDepth
+}
+
+func (r *renderer) startsBlock(line string) bool {
+	return fenceOf(line) != "" ||
+		r.startsQuote(line) ||
+		bulletItemRegex.MatchString(line) ||
+		orderedItemRegex.MatchString(line)
+}
+
+// unindent removes up to three leading spaces, more indentation is kept as content
+func unindent(line string) string {
+	for i := 0; i < 3 && strings.HasPrefix(line, " "); i++ {
+		line = line[1:]
+	}
+	return line
+}
+
+func isLanguageName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isWordChar(s[i]) && !strings.ContainsRune("+-#._", rune(s[i])) {
+			return false
+		}
+	}
+	return len(s) <= 32
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isPunctuation(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
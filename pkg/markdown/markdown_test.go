@@ -0,0 +1,235 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		wantHTML string
+		wantText string
+	}{
+		{
+			name:     "plain text",
+			source:   "hello",
+			wantHTML: "<p>hello</p>",
+			wantText: "hello",
+		},
+		{
+			name:     "line breaks",
+			source:   "first\r\nsecond",
+			wantHTML: "<p>first<br>second</p>",
+			wantText: "first\nsecond",
+		},
+		{
+			name:     "paragraphs",
+			source:   "first\n\nsecond",
+			wantHTML: "<p>first</p>\n<p>second</p>",
+			wantText: "first\nsecond",
+		},
+		{
+			name:     "bold and italics",
+			source:   "**bold** *italics* _italics_ __bold__",
+			wantHTML: "<p><strong>bold</strong> <em>italics</em> <em>italics</em> <strong>bold</strong></p>",
+			wantText: "bold italics italics bold",
+		},
+		{
+			name:     "nested emphasis",
+			source:   "***both*** **a *b* c** *a **b** c*",
+			wantHTML: "<p><strong><em>both</em></strong> <strong>a <em>b</em> c</strong> <em>a <strong>b</strong> c</em></p>",
+			wantText: "both a b c a b c",
+		},
+		{
+			name:     "underscores inside words",
+			source:   "snake_case_name",
+			wantHTML: "<p>snake_case_name</p>",
+			wantText: "snake_case_name",
+		},
+		{
+			name:     "unmatched delimiters",
+			source:   "2 * 3 and **open",
+			wantHTML: "<p>2 * 3 and **open</p>",
+			wantText: "2 * 3 and **open",
+		},
+		{
+			name:     "escaped delimiters",
+			source:   `\*not italics\*`,
+			wantHTML: "<p>*not italics*</p>",
+			wantText: "*not italics*",
+		},
+		{
+			name:     "code span",
+			source:   "run `go test ./...` or `` a ` b ``",
+			wantHTML: "<p>run <code>go test ./...</code> or <code>a ` b</code></p>",
+			wantText: "run go test ./... or a ` b",
+		},
+		{
+			name:     "no emphasis inside code span",
+			source:   "`**not bold**`",
+			wantHTML: "<p><code>**not bold**</code></p>",
+			wantText: "**not bold**",
+		},
+		{
+			name:     "fenced code block",
+			source:   "```go\nif a < b {\n\n}\n```\nafter",
+			wantHTML: "<pre><code class=\"language-go\">if a &lt; b {\n\n}</code></pre>\n<p>after</p>",
+			wantText: "if a < b {\n\n}\nafter",
+		},
+		{
+			name:     "unclosed fenced code block",
+			source:   "~~~\n**code**",
+			wantHTML: "<pre><code>**code**</code></pre>",
+			wantText: "**code**",
+		},
+		{
+			name:     "unsafe code block language",
+			source:   "```\"><script>\ncode\n```",
+			wantHTML: "<pre><code>code</code></pre>",
+			wantText: "code",
+		},
+		{
+			name:     "link",
+			source:   "see [the *docs*](https://example.com/a?b=1&c=2)",
+			wantHTML: `<p>see <a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">the <em>docs</em></a></p>`,
+			wantText: "see the docs (https://example.com/a?b=1&c=2)",
+		},
+		{
+			name:     "link labeled with its url",
+			source:   "[mailto:a@example.com](mailto:a@example.com)",
+			wantHTML: `<p><a href="mailto:a@example.com" rel="nofollow noopener noreferrer">mailto:a@example.com</a></p>`,
+			wantText: "mailto:a@example.com",
+		},
+		{
+			name:     "javascript link",
+			source:   "[click](JavaScript:alert(1))",
+			wantHTML: "<p>click</p>",
+			wantText: "click",
+		},
+		{
+			name:     "relative link",
+			source:   "[click](/admin)",
+			wantHTML: "<p>click</p>",
+			wantText: "click",
+		},
+		{
+			name:     "not a link",
+			source:   "[x] and [y](not a url)",
+			wantHTML: "<p>[x] and [y](not a url)</p>",
+			wantText: "[x] and [y](not a url)",
+		},
+		{
+			name:     "quote",
+			source:   "> quoted **text**\n> - item\nreply",
+			wantHTML: "<blockquote><p>quoted <strong>text</strong></p>\n<ul><li>item</li></ul></blockquote>\n<p>reply</p>",
+			wantText: "quoted text\nitem\nreply",
+		},
+		{
+			name:     "bullet list",
+			source:   "- one\n* *two*\n+ three",
+			wantHTML: "<ul><li>one</li><li><em>two</em></li><li>three</li></ul>",
+			wantText: "one\ntwo\nthree",
+		},
+		{
+			name:     "ordered list",
+			source:   "3. three\n4) four",
+			wantHTML: "<ol start=\"3\"><li>three</li><li>four</li></ol>",
+			wantText: "three\nfour",
+		},
+		{
+			name:     "list interrupts a paragraph",
+			source:   "todo:\n1. first",
+			wantHTML: "<p>todo:</p>\n<ol><li>first</li></ol>",
+			wantText: "todo:\nfirst",
+		},
+		{
+			name:     "raw html is escaped",
+			source:   `<img src=x onerror="alert(1)"> & <b>`,
+			wantHTML: "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt; &amp; &lt;b&gt;</p>",
+			wantText: `<img src=x onerror="alert(1)"> & <b>`,
+		},
+		{
+			name:     "attribute injection in link",
+			source:   `[x](https://example.com/"onmouseover="alert(1))`,
+			wantHTML: `<p><a href="https://example.com/&#34;onmouseover=&#34;alert(1)" rel="nofollow noopener noreferrer">x</a></p>`,
+			wantText: `x (https://example.com/"onmouseover="alert(1))`,
+		},
+		{
+			name:     "nested quote",
+			source:   ">> deep\n> shallow",
+			wantHTML: "<blockquote><blockquote><p>deep</p></blockquote>\n<p>shallow</p></blockquote>",
+			wantText: "deep\nshallow",
+		},
+		{
+			name:     "quotes nested too deep",
+			source:   strings.Repeat(">", 10) + " deep",
+			wantHTML: strings.Repeat("<blockquote>", 8) + "<p>&gt;&gt; deep</p>" + strings.Repeat("</blockquote>", 8),
+			wantText: ">> deep",
+		},
+		{
+			name:     "nested brackets in link label",
+			source:   "[a [b] c](https://example.com) [d",
+			wantHTML: `<p><a href="https://example.com" rel="nofollow noopener noreferrer">a [b] c</a> [d</p>`,
+			wantText: "a [b] c (https://example.com) [d",
+		},
+		{
+			name:     "empty",
+			source:   "",
+			wantHTML: "",
+			wantText: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rendered, err := Render(test.source)
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantHTML, rendered.HTML)
+			assert.Equal(t, test.wantText, rendered.Text)
+		})
+	}
+}
+
+func TestRenderTooLong(t *testing.T) {
+	_, err := Render(strings.Repeat("a", MaxSourceLength))
+	assert.NoError(t, err)
+
+	rendered, err := Render(strings.Repeat("a", MaxSourceLength+1))
+	assert.Nil(t, rendered)
+	assert.ErrorIs(t, err, ErrSourceTooLong)
+}
+
+func TestRenderPathological(t *testing.T) {
+	fill := func(pattern string) string {
+		return strings.Repeat(pattern, MaxSourceLength/len(pattern))
+	}
+
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "nested quotes", source: fill(">")},
+		{name: "quote lines", source: fill(">>>>>>>>>> a\n")},
+		{name: "unmatched brackets", source: fill("[")},
+		{name: "unmatched links", source: fill("[a](")},
+		{name: "nested links", source: fill("[[a](b)")},
+		{name: "unmatched emphasis", source: fill("*a ")},
+		{name: "delimiter run", source: fill("*")[1:] + "a"},
+		{name: "mixed delimiters", source: fill("*_")},
+		{name: "backtick runs", source: fill("` `` ")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Now()
+			_, err := Render(test.source)
+			assert.NoError(t, err)
+			assert.Less(t, time.Since(start), time.Second)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package mention
+
+import "strings"
+
+const (
+	// Here notifies the members of the room who are online
+	Here = "here"
+	// Room notifies every member of the room
+	Room = "room"
+)
+
+// Handles follow the same rule as user handles: 3 to 30 lowercase letters, digits or underscores
+const (
+	minHandleLength = 3
+	maxHandleLength = 30
+)
+
+// Mentions are the users and groups mentioned in a message
+type Mentions struct {
+	// Handles are the mentioned handles in order of first appearance, without duplicates
+	Handles []string
+	Here    bool
+	Room    bool
+}
+
+// Parse finds the @handle, @here and @room mentions in a plain text message.
+// A mention starts a word, so email addresses are not mentions, and it is matched
+// case-insensitively since handles are lowercase.
+func Parse(text string) *Mentions {
+	mentions := &Mentions{}
+	seen := map[string]bool{}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] != '@' || (i > 0 && (isHandleChar(text[i-1]) || text[i-1] == '@')) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(text) && isHandleChar(text[end]) {
+			end++
+		}
+		handle := strings.ToLower(text[i+1 : end])
+		i = end - 1
+
+		switch {
+		case handle == Here:
+			mentions.Here = true
+		case handle == Room:
+			mentions.Room = true
+		case len(handle) < minHandleLength || len(handle) > maxHandleLength:
+		case !seen[handle]:
+			seen[handle] = true
+			mentions.Handles = append(mentions.Handles, handle)
+		}
+	}
+
+	return mentions
+}
+
+// IsSpecial reports whether a handle is reserved for a group mention
+func IsSpecial(handle string) bool {
+	return handle == Here || handle == Room
+}
+
+func isHandleChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
@@ -0,0 +1,67 @@
+package mention
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want *Mentions
+	}{
+		{
+			name: "no mentions",
+			text: "hello world",
+			want: &Mentions{},
+		},
+		{
+			name: "handles in order without duplicates",
+			text: "@bob and @alice, then @bob again",
+			want: &Mentions{Handles: []string{"bob", "alice"}},
+		},
+		{
+			name: "handles are case-insensitive",
+			text: "hi @Alice_01!",
+			want: &Mentions{Handles: []string{"alice_01"}},
+		},
+		{
+			name: "special mentions",
+			text: "@here and @ROOM, ping @carol",
+			want: &Mentions{Handles: []string{"carol"}, Here: true, Room: true},
+		},
+		{
+			name: "email address is not a mention",
+			text: "mail alice@example.com",
+			want: &Mentions{},
+		},
+		{
+			name: "too short or too long",
+			text: "@ab @" + "abcdefghijklmnopqrstuvwxyz12345",
+			want: &Mentions{},
+		},
+		{
+			name: "mention after punctuation and line break",
+			text: "(@dave)\n@erin: ok",
+			want: &Mentions{Handles: []string{"dave", "erin"}},
+		},
+		{
+			name: "double at sign",
+			text: "@@frank",
+			want: &Mentions{},
+		},
+		{
+			name: "lone at sign",
+			text: "meet @ 5",
+			want: &Mentions{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, Parse(test.text))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Field is a member of a JSON Merge Patch (RFC 7396) document.
+// It tells apart a member that is absent, set to null, or set to a value.
+type Field[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// UnmarshalJSON is only called for members present in the document
+func (f *Field[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		f.Null = true
+		return nil
+	}
+
+	return json.Unmarshal(data, &f.Value)
+}
+
+// NewField creates a field set to a value
+func NewField[T any](value T) Field[T] {
+	return Field[T]{Set: true, Value: value}
+}
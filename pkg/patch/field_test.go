@@ -0,0 +1,64 @@
+package patch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldUnmarshalJSON(t *testing.T) {
+	type document struct {
+		Name Field[string] `json:"name"`
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    Field[string]
+		wantErr bool
+	}{
+		{
+			name:    "absent",
+			input:   `{}`,
+			want:    Field[string]{},
+			wantErr: false,
+		},
+		{
+			name:    "null",
+			input:   `{"name": null}`,
+			want:    Field[string]{Set: true, Null: true},
+			wantErr: false,
+		},
+		{
+			name:    "value",
+			input:   `{"name": "test"}`,
+			want:    Field[string]{Set: true, Value: "test"},
+			wantErr: false,
+		},
+		{
+			name:    "empty value",
+			input:   `{"name": ""}`,
+			want:    Field[string]{Set: true, Value: ""},
+			wantErr: false,
+		},
+		{
+			name:    "wrong type",
+			input:   `{"name": 1}`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var doc document
+			err := json.Unmarshal([]byte(test.input), &doc)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.want, doc.Name)
+			}
+		})
+	}
+}
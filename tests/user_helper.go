@@ -6,9 +6,10 @@ import (
 	"gorm.io/gorm"
 )
 
-func CreateTestUser(db *gorm.DB, name, email, password string) error {
+func CreateTestUser(db *gorm.DB, name, handle, email, password string) error {
 	user := &entity.User{
 		Name:     name,
+		Handle:   handle,
 		Email:    email,
 		Password: password,
 	}